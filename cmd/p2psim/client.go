@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// envelope: the response of every endpoint of the simulator
+type envelope struct {
+	Message string          `json:"message"`
+	Error   bool            `json:"error"`
+	Code    string          `json:"code"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// apiError: a response with the error flag set
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e apiError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s (%d)", e.Message, e.Status)
+	}
+
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// client: talks to the REST API of the simulator
+type client struct {
+	base string
+	http *http.Client
+}
+
+// newClient: creates a client for the simulator at the given base url
+func newClient(base string) client {
+	return client{
+		base: strings.TrimRight(base, "/"),
+		http: &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+/*
+do: sends the request and decodes the data of the response into out, if it is not nil.
+The error flag of the response is returned as an apiError
+*/
+func (c client) do(method string, path string, body interface{}, out interface{}) (string, error) {
+	var reader io.Reader
+
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		return "", err
+	}
+
+	if body != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var response envelope
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: unexpected response (%d): %s", method, path, res.StatusCode, err.Error())
+	}
+
+	if response.Error {
+		return "", apiError{Status: res.StatusCode, Code: response.Code, Message: response.Message}
+	}
+
+	if out != nil && len(response.Data) > 0 {
+		err = json.Unmarshal(response.Data, out)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return response.Message, nil
+}
+
+// node: request body of join and of source
+type node struct {
+	Id       int `json:"id"`
+	Capacity int `json:"capacity"`
+}
+
+// reassignment: a peer attached to a different parent after a leave
+type reassignment struct {
+	Id        int  `json:"id"`
+	OldParent int  `json:"old_parent"`
+	NewParent int  `json:"new_parent"`
+	Root      bool `json:"root"`
+}
+
+// leaveReport: data of the leave response
+type leaveReport struct {
+	Id            int            `json:"id"`
+	Reassignments []reassignment `json:"reassignments"`
+	Dropped       []int          `json:"dropped"`
+}
+
+// stats: data of the stats response
+type stats struct {
+	Peers        int `json:"peers"`
+	Trees        int `json:"trees"`
+	MaxDepth     int `json:"max_depth"`
+	FreeCapacity int `json:"free_capacity"`
+	Reorders     int `json:"reorders"`
+	Rehomed      int `json:"rehomed"`
+}
+
+// Join: joins the node to the network
+func (c client) Join(n node) (string, error) {
+	return c.do(http.MethodPost, "/join", n, nil)
+}
+
+// Source: adds the node to the network as a source
+func (c client) Source(n node) (string, error) {
+	return c.do(http.MethodPost, "/sources", n, nil)
+}
+
+// Leave: removes the node from the network
+func (c client) Leave(id int) (leaveReport, error) {
+	var report leaveReport
+
+	_, err := c.do(http.MethodDelete, fmt.Sprintf("/leave/%d", id), nil, &report)
+
+	return report, err
+}
+
+// Trace: returns the trees of the network drawn by the simulator, empty if there are none
+func (c client) Trace() (string, error) {
+	path := "/trace?format=text"
+
+	res, err := c.http.Get(c.base + path)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode == http.StatusOK {
+		return string(body), nil
+	}
+
+	// the errors are envelopes like the other responses
+	var response envelope
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: unexpected response (%d): %s", http.MethodGet, path, res.StatusCode, err.Error())
+	}
+
+	return "", apiError{Status: res.StatusCode, Code: response.Code, Message: response.Message}
+}
+
+// Stats: returns the statistics of the network
+func (c client) Stats() (stats, error) {
+	var s stats
+
+	_, err := c.do(http.MethodGet, "/stats", nil, &s)
+
+	return s, err
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command: runs a subcommand with its arguments
+type command func(ctx context.Context, c client, args []string, stdout io.Writer) error
+
+// usageError: the arguments of a command are not valid
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// commands: every subcommand by name
+var commands = map[string]command{
+	"join":   join,
+	"source": source,
+	"leave":  leave,
+	"trace":  trace,
+	"stats":  showStats,
+	"watch":  watch,
+	"load":   load,
+}
+
+// parseInts: converts the arguments to integers
+func parseInts(args []string, names ...string) ([]int, error) {
+	if len(args) != len(names) {
+		return nil, usageError(fmt.Sprintf("expected %d arguments: %s", len(names), strings.Join(names, " ")))
+	}
+
+	values := make([]int, 0, len(args))
+
+	for index, arg := range args {
+		value, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, usageError(fmt.Sprintf("%s must be an integer, but got %q", names[index], arg))
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// join: join <id> <capacity>
+func join(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	values, err := parseInts(args, "<id>", "<capacity>")
+	if err != nil {
+		return err
+	}
+
+	message, err := c.Join(node{Id: values[0], Capacity: values[1]})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d: %s\n", values[0], message)
+	return nil
+}
+
+// source: source <id> <capacity>
+func source(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	values, err := parseInts(args, "<id>", "<capacity>")
+	if err != nil {
+		return err
+	}
+
+	message, err := c.Source(node{Id: values[0], Capacity: values[1]})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d: %s\n", values[0], message)
+	return nil
+}
+
+// leave: leave <id>, prints the peers attached to a different parent
+func leave(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	values, err := parseInts(args, "<id>")
+	if err != nil {
+		return err
+	}
+
+	report, err := c.Leave(values[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d: successfully left\n", report.Id)
+
+	for _, r := range report.Reassignments {
+		fmt.Fprintf(stdout, "  %d: %s -> %s\n", r.Id, parentName(r.OldParent), parentName(r.NewParent))
+	}
+
+	for _, id := range report.Dropped {
+		fmt.Fprintf(stdout, "  %d: dropped\n", id)
+	}
+
+	return nil
+}
+
+// parentName: parent id or root, if the peer has no parent
+func parentName(id int) string {
+	if id == 0 {
+		return "root"
+	}
+
+	return strconv.Itoa(id)
+}
+
+// trace: draws the trees of the network
+func trace(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return usageError("trace takes no arguments")
+	}
+
+	drawing, err := draw(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(stdout, drawing)
+	return nil
+}
+
+// draw: returns the trees of the network as the simulator draws them
+func draw(c client) (string, error) {
+	drawing, err := c.Trace()
+	if err != nil {
+		return "", err
+	}
+
+	if drawing == "" {
+		return "empty network\n", nil
+	}
+
+	return drawing, nil
+}
+
+// showStats: prints the statistics of the network
+func showStats(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return usageError("stats takes no arguments")
+	}
+
+	s, err := c.Stats()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "peers\t%d\n", s.Peers)
+	fmt.Fprintf(w, "trees\t%d\n", s.Trees)
+	fmt.Fprintf(w, "max depth\t%d\n", s.MaxDepth)
+	fmt.Fprintf(w, "free capacity\t%d\n", s.FreeCapacity)
+	fmt.Fprintf(w, "reorders\t%d\n", s.Reorders)
+	fmt.Fprintf(w, "rehomed\t%d\n", s.Rehomed)
+
+	return w.Flush()
+}
+
+// watch: polls the trace and redraws the trees whenever they change, until interrupted
+func watch(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	interval := fs.Duration("interval", time.Second, "polling interval")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	if *interval <= 0 {
+		return usageError("interval must be positive")
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	previous := ""
+
+	for {
+		drawing, err := draw(c)
+		if err != nil {
+			return err
+		}
+
+		if drawing != previous {
+			// clear the screen before redrawing
+			fmt.Fprint(stdout, "\033[H\033[2J")
+			fmt.Fprintf(stdout, "%s\n%s", time.Now().Format(time.RFC3339), drawing)
+
+			previous = drawing
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// load: joins the nodes of the file in order. Continues after a failed join, and fails if any of them failed
+func load(ctx context.Context, c client, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageError("expected 1 argument: <file>")
+	}
+
+	nodes, err := readNodes(args[0])
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for _, n := range nodes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		_, err = c.Join(n)
+		if err != nil {
+			fmt.Fprintf(stdout, "%d: %s\n", n.Id, err.Error())
+			failed++
+		}
+	}
+
+	fmt.Fprintf(stdout, "joined %d of %d nodes\n", len(nodes)-failed, len(nodes))
+
+	if failed > 0 {
+		return fmt.Errorf("%d nodes failed to join", failed)
+	}
+
+	return nil
+}
+
+/*
+readNodes: reads the nodes of a JSON file, an array of {"id": 1, "capacity": 2},
+or of a text file with one "id capacity" per line. Empty lines and lines starting with # are skipped
+*/
+func readNodes(path string) ([]node, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]node, 0)
+
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(content, &nodes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err.Error())
+		}
+
+		return nodes, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		values, err := parseInts(strings.Fields(strings.ReplaceAll(text, ",", " ")), "id", "capacity")
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err.Error())
+		}
+
+		nodes = append(nodes, node{Id: values[0], Capacity: values[1]})
+	}
+
+	return nodes, scanner.Err()
+}
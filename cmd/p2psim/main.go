@@ -0,0 +1,91 @@
+/*
+p2psim: command line client of the p2p network simulator.
+
+	p2psim [-url http://localhost:8080] <command> [arguments]
+
+The base url can also be given by the P2PSIM_URL environment variable.
+Exits with a non zero status, if the simulator responds with an error.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const defaultURL = "http://localhost:8080"
+
+const usage = `usage: p2psim [-url base url] <command> [arguments]
+
+commands:
+  join <id> <capacity>    join a node to the network
+  source <id> <capacity>  add a source, the root of a new tree
+  leave <id>              remove a node from the network
+  trace                   draw the trees of the network
+  stats                   show the statistics of the network
+  watch [-interval 1s]    redraw the trees whenever the network changes
+  load <file>             join the nodes of the file, one "id capacity" per line or a JSON array
+`
+
+// exit statuses
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run: runs the command of the given arguments and returns the exit status
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string) int {
+	fs := flag.NewFlagSet("p2psim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+
+	base := getenv("P2PSIM_URL")
+	if base == "" {
+		base = defaultURL
+	}
+
+	url := fs.String("url", base, "base url of the simulator")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	err = command(ctx, newClient(*url), fs.Args()[1:], stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err.Error())
+
+		if _, ok := err.(usageError); ok {
+			return exitUsage
+		}
+
+		return exitError
+	}
+
+	return exitOK
+}
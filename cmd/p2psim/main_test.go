@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSimulator: responds to the requests with the envelopes of the simulator
+func fakeSimulator(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/join", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if strings.Contains(string(body), `"id":1,`) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"id 1 already reserved","error":true,"code":"duplicate_id","data":null}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"successfully joined","error":false,"data":2}`))
+	})
+
+	mux.HandleFunc("/sources", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"source added","error":false,"data":5}`))
+	})
+
+	mux.HandleFunc("/leave/3", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"message":"successfully left","error":false,"data":{"id":3,"reassignments":[{"id":4,"old_parent":3,"new_parent":0,"root":true}],"dropped":[6]}}`))
+	})
+
+	mux.HandleFunc("/trace", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "text" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"text format expected","error":true,"data":null}`))
+			return
+		}
+
+		w.Write([]byte("2 (1/1) depth 0\n└── 4 (0/0) depth 1\n"))
+	})
+
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"stats received","error":false,"data":{"peers":2,"trees":1,"max_depth":1,"free_capacity":0,"reorders":3,"rehomed":1}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRun(t *testing.T) {
+	server := fakeSimulator(t)
+
+	dir := t.TempDir()
+
+	text := filepath.Join(dir, "nodes.txt")
+	os.WriteFile(text, []byte("# id capacity\n2 1\n\n4, 0\n"), 0644)
+
+	withDuplicate := filepath.Join(dir, "nodes.json")
+	os.WriteFile(withDuplicate, []byte(`[{"id":2,"capacity":1},{"id":1,"capacity":0}]`), 0644)
+
+	testTable := []struct {
+		name     string
+		args     []string
+		status   int
+		expected string
+	}{
+		{
+			name:     "join",
+			args:     []string{"join", "2", "1"},
+			status:   exitOK,
+			expected: "2: successfully joined\n",
+		},
+		{
+			name:   "join error envelope",
+			args:   []string{"join", "1", "1"},
+			status: exitError,
+		},
+		{
+			name:   "join with missing capacity",
+			args:   []string{"join", "2"},
+			status: exitUsage,
+		},
+		{
+			name:     "source",
+			args:     []string{"source", "5", "2"},
+			status:   exitOK,
+			expected: "5: source added\n",
+		},
+		{
+			name:     "leave",
+			args:     []string{"leave", "3"},
+			status:   exitOK,
+			expected: "3: successfully left\n  4: 3 -> root\n  6: dropped\n",
+		},
+		{
+			name:     "trace",
+			args:     []string{"trace"},
+			status:   exitOK,
+			expected: "2 (1/1) depth 0\n└── 4 (0/0) depth 1\n",
+		},
+		{
+			name:     "stats",
+			args:     []string{"stats"},
+			status:   exitOK,
+			expected: "peers          2\ntrees          1\nmax depth      1\nfree capacity  0\nreorders       3\nrehomed        1\n",
+		},
+		{
+			name:     "load text file",
+			args:     []string{"load", text},
+			status:   exitOK,
+			expected: "joined 2 of 2 nodes\n",
+		},
+		{
+			name:     "load with a failed join",
+			args:     []string{"load", withDuplicate},
+			status:   exitError,
+			expected: "1: id 1 already reserved (409 duplicate_id)\njoined 1 of 2 nodes\n",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"unknown"},
+			status: exitUsage,
+		},
+		{
+			name:   "no command",
+			args:   []string{},
+			status: exitUsage,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			getenv := func(key string) string {
+				if key == "P2PSIM_URL" {
+					return server.URL
+				}
+
+				return ""
+			}
+
+			status := run(context.Background(), testCase.args, &stdout, &stderr, getenv)
+
+			if status != testCase.status {
+				t.Errorf("expected %v, but got %v (%s)", testCase.status, status, stderr.String())
+			}
+
+			if stdout.String() != testCase.expected {
+				t.Errorf("expected %q, but got %q", testCase.expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	server := fakeSimulator(t)
+
+	var stdout, stderr bytes.Buffer
+
+	// stops after the first drawing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	status := run(ctx, []string{"-url", server.URL, "watch", "-interval", "10ms"}, &stdout, &stderr, func(string) string { return "" })
+
+	if status != exitOK {
+		t.Errorf("expected %v, but got %v (%s)", exitOK, status, stderr.String())
+	}
+
+	if !strings.Contains(stdout.String(), "2 (1/1) depth 0\n└── 4 (0/0) depth 1\n") {
+		t.Errorf("expected the trees to be drawn, but got %q", stdout.String())
+	}
+}
+
+func TestTraceMesh(t *testing.T) {
+	// the simulator draws the mesh one peer per line with its parents, the client prints it as it is
+	drawing := "1 (2/2) depth 0\n2 (1/1) depth 1 <- 1\n3 (1/1) depth 1 <- 1\n4 (0/0) depth 2 <- 2, 3\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(drawing))
+	}))
+	t.Cleanup(server.Close)
+
+	var stdout, stderr bytes.Buffer
+
+	status := run(context.Background(), []string{"-url", server.URL, "trace"}, &stdout, &stderr, func(string) string { return "" })
+
+	if status != exitOK {
+		t.Errorf("expected %v, but got %v (%s)", exitOK, status, stderr.String())
+	}
+
+	if stdout.String() != drawing {
+		t.Errorf("expected %q, but got %q", drawing, stdout.String())
+	}
+}
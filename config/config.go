@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage"
+	"p2p-network-simulator/storage/treap"
+)
+
+// Config: settings of the application
+type Config struct {
+	// address the HTTP server listens on
+	Address string
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// max size of a request body in bytes
+	MaxBodySize int64
+
+	// default placement strategy: decides the parent among peers which have the same free capacity
+	Placement string
+
+	// seeds any randomness of the placement strategy
+	Seed int64
+
+	// merges the forest after each join and leave
+	AutoMerge bool
+
+	// moves joining peers with more free capacity than their parent towards the root
+	Promote bool
+
+	// time taken to detect a crashed peer
+	DetectionDelay time.Duration
+
+	// peers without a heartbeat within this time are evicted. zero disables the eviction
+	TTL time.Duration
+
+	// max number of operations which can be undone. zero disables the history
+	HistorySize int
+
+	// max depth of a peer in its tree. zero means no limit
+	MaxDepth int
+
+	// places peers beneath parents in the same region, or the closest ones
+	Locality bool
+
+	// peers are stable once their mean session reaches this time, and are preferred as interior peers. zero disables it
+	StableAfter time.Duration
+
+	// shape of the network: tree, mesh where a peer can have more than one parent,
+	// or stripes where each stripe of the content has its own trees
+	Topology string
+
+	// number of stripes the content is split into in the stripes topology
+	Stripes int
+
+	// max number of parents of a peer in the mesh topology
+	Parents int
+
+	// only sources can be the roots of the trees
+	SourcesOnly bool
+
+	// order of the peers waiting for free capacity: none, fifo or priority
+	Queue string
+
+	// the topology is restored from this file on start and saved to it on shutdown. empty disables it
+	SnapshotPath string
+
+	// debug, info or error
+	LogLevel string
+
+	// logfmt or json
+	LogFormat string
+}
+
+// Default: returns the default settings
+func Default() Config {
+	return Config{
+		Address:        "0.0.0.0:8080",
+		ReadTimeout:    time.Second * 10,
+		WriteTimeout:   time.Second * 10,
+		IdleTimeout:    time.Second * 10,
+		MaxBodySize:    1 << 20,
+		Placement:      treap.ShallowestDepth.String(),
+		Seed:           0,
+		AutoMerge:      false,
+		Promote:        false,
+		DetectionDelay: 0,
+		TTL:            0,
+		HistorySize:    100,
+		MaxDepth:       0,
+		Locality:       false,
+		StableAfter:    0,
+		Topology:       "tree",
+		Parents:        2,
+		Stripes:        2,
+		SourcesOnly:    false,
+		Queue:          storage.NoQueue.String(),
+		SnapshotPath:   "",
+		LogLevel:       "info",
+		LogFormat:      "logfmt",
+	}
+}
+
+// Validate: returns an error if any of the settings is not valid
+func (c Config) Validate() error {
+	if strings.TrimSpace(c.Address) == "" {
+		return errors.New("address must not be empty")
+	}
+
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.IdleTimeout <= 0 {
+		return errors.New("timeouts must be positive")
+	}
+
+	if c.MaxBodySize <= 0 {
+		return errors.New("max body size must be positive")
+	}
+
+	if _, ok := treap.ParseTieBreak(c.Placement); !ok {
+		return fmt.Errorf("unknown placement strategy %q", c.Placement)
+	}
+
+	if c.DetectionDelay < 0 {
+		return errors.New("detection delay must be none negative")
+	}
+
+	if c.TTL < 0 {
+		return errors.New("ttl must be none negative")
+	}
+
+	if c.StableAfter < 0 {
+		return errors.New("stable after must be none negative")
+	}
+
+	if c.HistorySize < 0 {
+		return errors.New("history size must be none negative")
+	}
+
+	if c.MaxDepth < 0 {
+		return errors.New("max depth must be none negative")
+	}
+
+	if c.Topology != "tree" && c.Topology != "mesh" && c.Topology != "stripes" {
+		return fmt.Errorf("unknown topology %q", c.Topology)
+	}
+
+	if c.Parents <= 0 {
+		return errors.New("parents must be positive")
+	}
+
+	if c.Stripes <= 0 {
+		return errors.New("stripes must be positive")
+	}
+
+	// the mesh and the stripes would ignore these settings, so they have to keep their defaults
+	if c.Topology != "tree" {
+		defaults := Default()
+
+		type setting struct {
+			name string
+			set  bool
+		}
+
+		ignored := []setting{
+			{name: "snapshot path", set: c.SnapshotPath != defaults.SnapshotPath}, // the snapshot keeps a single tree for each peer
+			{name: "queue", set: c.Queue != defaults.Queue},
+			{name: "sources only", set: c.SourcesOnly != defaults.SourcesOnly},
+			{name: "history size", set: c.HistorySize != defaults.HistorySize},
+			{name: "detection delay", set: c.DetectionDelay != defaults.DetectionDelay},
+		}
+
+		// the stripes are trees, so only the mesh ignores the settings of the trees
+		if c.Topology == "mesh" {
+			ignored = append(ignored, []setting{
+				{name: "max depth", set: c.MaxDepth != defaults.MaxDepth},
+				{name: "auto merge", set: c.AutoMerge != defaults.AutoMerge},
+				{name: "promote", set: c.Promote != defaults.Promote},
+				{name: "locality", set: c.Locality != defaults.Locality},
+				{name: "stable after", set: c.StableAfter != defaults.StableAfter},
+			}...)
+		}
+
+		for _, s := range ignored {
+			if s.set {
+				return fmt.Errorf("%s is not supported by the %s topology", s.name, c.Topology)
+			}
+		}
+	}
+
+	if _, ok := storage.ParseQueuePolicy(c.Queue); !ok {
+		return fmt.Errorf("unknown queue policy %q", c.Queue)
+	}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+
+	if c.LogFormat != "logfmt" && c.LogFormat != "json" {
+		return fmt.Errorf("unknown log format %q", c.LogFormat)
+	}
+
+	return nil
+}
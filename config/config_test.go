@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	testTable := []struct {
+		name          string
+		change        func(c *Config)
+		expectedError error
+	}{
+		{
+			name:          "defaults",
+			change:        func(c *Config) {},
+			expectedError: nil,
+		},
+		{
+			name:          "empty address",
+			change:        func(c *Config) { c.Address = " " },
+			expectedError: errors.New("address must not be empty"),
+		},
+		{
+			name:          "zero timeout",
+			change:        func(c *Config) { c.ReadTimeout = 0 },
+			expectedError: errors.New("timeouts must be positive"),
+		},
+		{
+			name:          "negative body size",
+			change:        func(c *Config) { c.MaxBodySize = -1 },
+			expectedError: errors.New("max body size must be positive"),
+		},
+		{
+			name:          "unknown placement",
+			change:        func(c *Config) { c.Placement = "capacity" },
+			expectedError: errors.New(`unknown placement strategy "capacity"`),
+		},
+		{
+			name:          "negative detection delay",
+			change:        func(c *Config) { c.DetectionDelay = -time.Second },
+			expectedError: errors.New("detection delay must be none negative"),
+		},
+		{
+			name:          "negative ttl",
+			change:        func(c *Config) { c.TTL = -time.Second },
+			expectedError: errors.New("ttl must be none negative"),
+		},
+		{
+			name:          "negative history size",
+			change:        func(c *Config) { c.HistorySize = -1 },
+			expectedError: errors.New("history size must be none negative"),
+		},
+		{
+			name:          "negative max depth",
+			change:        func(c *Config) { c.MaxDepth = -1 },
+			expectedError: errors.New("max depth must be none negative"),
+		},
+		{
+			name:          "unknown topology",
+			change:        func(c *Config) { c.Topology = "ring" },
+			expectedError: errors.New(`unknown topology "ring"`),
+		},
+		{
+			name:          "zero parents",
+			change:        func(c *Config) { c.Parents = 0 },
+			expectedError: errors.New("parents must be positive"),
+		},
+		{
+			name:          "negative stable after",
+			change:        func(c *Config) { c.StableAfter = -time.Minute },
+			expectedError: errors.New("stable after must be none negative"),
+		},
+		{
+			name:          "zero stripes",
+			change:        func(c *Config) { c.Stripes = 0 },
+			expectedError: errors.New("stripes must be positive"),
+		},
+		{
+			name:          "stripes with snapshot path",
+			change:        func(c *Config) { c.Topology = "stripes"; c.SnapshotPath = "network.json" },
+			expectedError: errors.New("snapshot path is not supported by the stripes topology"),
+		},
+		{
+			name:          "mesh with snapshot path",
+			change:        func(c *Config) { c.Topology = "mesh"; c.SnapshotPath = "network.json" },
+			expectedError: errors.New("snapshot path is not supported by the mesh topology"),
+		},
+		{
+			name:          "stripes with a queue",
+			change:        func(c *Config) { c.Topology = "stripes"; c.Queue = "fifo" },
+			expectedError: errors.New("queue is not supported by the stripes topology"),
+		},
+		{
+			name:          "stripes with sources only",
+			change:        func(c *Config) { c.Topology = "stripes"; c.SourcesOnly = true },
+			expectedError: errors.New("sources only is not supported by the stripes topology"),
+		},
+		{
+			name:          "stripes with a history size",
+			change:        func(c *Config) { c.Topology = "stripes"; c.HistorySize = 10 },
+			expectedError: errors.New("history size is not supported by the stripes topology"),
+		},
+		{
+			name:          "stripes with a detection delay",
+			change:        func(c *Config) { c.Topology = "stripes"; c.DetectionDelay = time.Second },
+			expectedError: errors.New("detection delay is not supported by the stripes topology"),
+		},
+		{
+			name:          "stripes with a max depth",
+			change:        func(c *Config) { c.Topology = "stripes"; c.MaxDepth = 3 },
+			expectedError: nil,
+		},
+		{
+			name:          "mesh with a max depth",
+			change:        func(c *Config) { c.Topology = "mesh"; c.MaxDepth = 3 },
+			expectedError: errors.New("max depth is not supported by the mesh topology"),
+		},
+		{
+			name:          "mesh with locality",
+			change:        func(c *Config) { c.Topology = "mesh"; c.Locality = true },
+			expectedError: errors.New("locality is not supported by the mesh topology"),
+		},
+		{
+			name:          "mesh with defaults",
+			change:        func(c *Config) { c.Topology = "mesh" },
+			expectedError: nil,
+		},
+		{
+			name:          "unknown queue policy",
+			change:        func(c *Config) { c.Queue = "lifo" },
+			expectedError: errors.New(`unknown queue policy "lifo"`),
+		},
+		{
+			name:          "unknown log level",
+			change:        func(c *Config) { c.LogLevel = "warn" },
+			expectedError: errors.New(`unknown log level "warn"`),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := Default()
+			testCase.change(&config)
+
+			err := config.Validate()
+
+			if err == nil && testCase.expectedError != nil {
+				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), err)
+			}
+
+			if err != nil && testCase.expectedError == nil {
+				t.Errorf("expected %v, but got %s", testCase.expectedError, err.Error())
+			}
+
+			if err != nil && testCase.expectedError != nil && err.Error() != testCase.expectedError.Error() {
+				t.Errorf("expected %s, but got %s", testCase.expectedError.Error(), err.Error())
+			}
+		})
+	}
+}
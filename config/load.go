@@ -0,0 +1,316 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting: a single setting which can be given by a flag, an environment variable or a file key
+type setting struct {
+	name  string // flag name and file key
+	env   string // environment variable
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// settings: every setting of the application
+var settings = []setting{
+	{
+		name:  "address",
+		env:   "P2P_ADDRESS",
+		usage: "address the HTTP server listens on",
+		set:   func(c *Config, v string) error { c.Address = v; return nil },
+	},
+	{
+		name:  "read-timeout",
+		env:   "P2P_READ_TIMEOUT",
+		usage: "max duration for reading a request",
+		set:   func(c *Config, v string) error { return parseDuration(&c.ReadTimeout, v) },
+	},
+	{
+		name:  "write-timeout",
+		env:   "P2P_WRITE_TIMEOUT",
+		usage: "max duration for writing a response",
+		set:   func(c *Config, v string) error { return parseDuration(&c.WriteTimeout, v) },
+	},
+	{
+		name:  "idle-timeout",
+		env:   "P2P_IDLE_TIMEOUT",
+		usage: "max duration to wait for the next request on a keep alive connection",
+		set:   func(c *Config, v string) error { return parseDuration(&c.IdleTimeout, v) },
+	},
+	{
+		name:  "max-body-size",
+		env:   "P2P_MAX_BODY_SIZE",
+		usage: "max size of a request body in bytes",
+		set:   func(c *Config, v string) error { return parseInt(&c.MaxBodySize, v) },
+	},
+	{
+		name:  "placement",
+		env:   "P2P_PLACEMENT",
+		usage: "placement strategy for peers with the same free capacity: depth, id or random",
+		set:   func(c *Config, v string) error { c.Placement = v; return nil },
+	},
+	{
+		name:  "seed",
+		env:   "P2P_SEED",
+		usage: "seed of the random placement strategy",
+		set:   func(c *Config, v string) error { return parseInt(&c.Seed, v) },
+	},
+	{
+		name:  "auto-merge",
+		env:   "P2P_AUTO_MERGE",
+		usage: "merge the trees after each join and leave",
+		set:   func(c *Config, v string) error { return parseBool(&c.AutoMerge, v) },
+	},
+	{
+		name:  "promote",
+		env:   "P2P_PROMOTE",
+		usage: "move joining peers with more free capacity than their parent towards the root",
+		set:   func(c *Config, v string) error { return parseBool(&c.Promote, v) },
+	},
+	{
+		name:  "detection-delay",
+		env:   "P2P_DETECTION_DELAY",
+		usage: "time taken to detect a crashed peer",
+		set:   func(c *Config, v string) error { return parseDuration(&c.DetectionDelay, v) },
+	},
+	{
+		name:  "ttl",
+		env:   "P2P_TTL",
+		usage: "evict peers without a heartbeat within this time, zero disables the eviction",
+		set:   func(c *Config, v string) error { return parseDuration(&c.TTL, v) },
+	},
+	{
+		name:  "history-size",
+		env:   "P2P_HISTORY_SIZE",
+		usage: "max number of operations which can be undone, zero disables the history",
+		set:   func(c *Config, v string) error { return parseSize(&c.HistorySize, v) },
+	},
+	{
+		name:  "max-depth",
+		env:   "P2P_MAX_DEPTH",
+		usage: "max depth of a peer in its tree, zero means no limit",
+		set:   func(c *Config, v string) error { return parseSize(&c.MaxDepth, v) },
+	},
+	{
+		name:  "locality",
+		env:   "P2P_LOCALITY",
+		usage: "place peers beneath parents in the same region, or the closest ones, instead of the ones with the most free capacity",
+		set:   func(c *Config, v string) error { return parseBool(&c.Locality, v) },
+	},
+	{
+		name:  "stable-after",
+		env:   "P2P_STABLE_AFTER",
+		usage: "time after which a peer with long sessions and few failures is preferred as an interior peer, 0s disables it",
+		set:   func(c *Config, v string) error { return parseDuration(&c.StableAfter, v) },
+	},
+	{
+		name:  "topology",
+		env:   "P2P_TOPOLOGY",
+		usage: "shape of the network: tree, mesh where a peer can have more than one parent, or stripes",
+		set:   func(c *Config, v string) error { c.Topology = v; return nil },
+	},
+	{
+		name:  "parents",
+		env:   "P2P_PARENTS",
+		usage: "max number of parents of a peer in the mesh topology, drawn from distinct subtrees",
+		set:   func(c *Config, v string) error { return parseSize(&c.Parents, v) },
+	},
+	{
+		name:  "stripes",
+		env:   "P2P_STRIPES",
+		usage: "number of stripes the content is split into in the stripes topology, each peer forwards one of them",
+		set:   func(c *Config, v string) error { return parseSize(&c.Stripes, v) },
+	},
+	{
+		name:  "sources-only",
+		env:   "P2P_SOURCES_ONLY",
+		usage: "only sources can be the roots of the trees, peers without a place are rejected",
+		set:   func(c *Config, v string) error { return parseBool(&c.SourcesOnly, v) },
+	},
+	{
+		name:  "queue",
+		env:   "P2P_QUEUE",
+		usage: "peers without a place wait for free capacity: none, fifo or priority",
+		set:   func(c *Config, v string) error { c.Queue = v; return nil },
+	},
+	{
+		name:  "snapshot-path",
+		env:   "P2P_SNAPSHOT_PATH",
+		usage: "file to restore the topology from on start and save it to on shutdown",
+		set:   func(c *Config, v string) error { c.SnapshotPath = v; return nil },
+	},
+	{
+		name:  "log-level",
+		env:   "P2P_LOG_LEVEL",
+		usage: "log level: debug, info or error",
+		set:   func(c *Config, v string) error { c.LogLevel = v; return nil },
+	},
+	{
+		name:  "log-format",
+		env:   "P2P_LOG_FORMAT",
+		usage: "log format: logfmt or json",
+		set:   func(c *Config, v string) error { c.LogFormat = v; return nil },
+	},
+}
+
+/*
+Load: builds the settings from the defaults, an optional file, environment variables and flags.
+Later sources take precedence over earlier ones:
+
+	defaults < file (-config or P2P_CONFIG, .json, .yaml or .yml) < environment variables < flags
+
+The settings are validated before returning.
+*/
+func Load(args []string, getenv func(string) string) (Config, error) {
+	config := Default()
+
+	// collect the flags first, they are applied last
+	fs := flag.NewFlagSet("p2p-network-simulator", flag.ContinueOnError)
+
+	path := fs.String("config", getenv("P2P_CONFIG"), "settings file (.json, .yaml or .yml)")
+	flags := make(map[string]string)
+
+	for _, s := range settings {
+		name := s.name
+
+		fs.Func(name, s.usage, func(v string) error {
+			flags[name] = v
+			return nil
+		})
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return config, err
+	}
+
+	if *path != "" {
+		err = loadFile(&config, *path)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	for _, s := range settings {
+		v := getenv(s.env)
+		if v == "" {
+			continue
+		}
+
+		err = s.set(&config, v)
+		if err != nil {
+			return config, fmt.Errorf("%s: %s", s.env, err.Error())
+		}
+	}
+
+	for _, s := range settings {
+		v, ok := flags[s.name]
+		if !ok {
+			continue
+		}
+
+		err = s.set(&config, v)
+		if err != nil {
+			return config, fmt.Errorf("-%s: %s", s.name, err.Error())
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// loadFile: applies the settings of the given JSON or YAML file
+func loadFile(config *Config, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+
+	switch filepath.Ext(path) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+
+		err = decoder.Decode(&values)
+
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+
+	default:
+		return fmt.Errorf("unknown config file type %q", filepath.Ext(path))
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	known := make(map[string]setting)
+	for _, s := range settings {
+		known[s.name] = s
+	}
+
+	for key, value := range values {
+		s, ok := known[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+
+		err = s.set(config, fmt.Sprint(value))
+		if err != nil {
+			return fmt.Errorf("%s: %s: %s", path, key, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func parseDuration(target *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*target = d
+	return nil
+}
+
+func parseInt(target *int64, value string) error {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*target = i
+	return nil
+}
+
+func parseSize(target *int, value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*target = i
+	return nil
+}
+
+func parseBool(target *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*target = b
+	return nil
+}
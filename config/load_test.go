@@ -0,0 +1,133 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile: writes the given content into a temporary file and returns the path
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	jsonFile := writeFile(t, "config.json", `{"address":"127.0.0.1:9000","max-body-size":2048,"auto-merge":true,"ttl":"30s"}`)
+	yamlFile := writeFile(t, "config.yaml", "address: 127.0.0.1:9001\nread-timeout: 5s\nplacement: id\n")
+	unknownKey := writeFile(t, "unknown.json", `{"port":8080}`)
+	unknownType := writeFile(t, "config.toml", `address = "127.0.0.1"`)
+
+	testTable := []struct {
+		name          string
+		args          []string
+		env           map[string]string
+		check         func(c Config) bool
+		expectedError bool
+	}{
+		{
+			name:  "defaults",
+			args:  nil,
+			env:   nil,
+			check: func(c Config) bool { return c == Default() },
+		},
+		{
+			name: "json file",
+			args: []string{"-config", jsonFile},
+			env:  nil,
+			check: func(c Config) bool {
+				return c.Address == "127.0.0.1:9000" && c.MaxBodySize == 2048 && c.AutoMerge && c.TTL == time.Second*30
+			},
+		},
+		{
+			name: "yaml file from environment",
+			args: nil,
+			env:  map[string]string{"P2P_CONFIG": yamlFile},
+			check: func(c Config) bool {
+				return c.Address == "127.0.0.1:9001" && c.ReadTimeout == time.Second*5 && c.Placement == "id"
+			},
+		},
+		{
+			name:  "environment overrides file",
+			args:  []string{"-config", yamlFile},
+			env:   map[string]string{"P2P_ADDRESS": "127.0.0.1:9002"},
+			check: func(c Config) bool { return c.Address == "127.0.0.1:9002" && c.Placement == "id" },
+		},
+		{
+			name:  "flags override environment",
+			args:  []string{"-config", yamlFile, "-address", "127.0.0.1:9003", "-log-level", "debug"},
+			env:   map[string]string{"P2P_ADDRESS": "127.0.0.1:9002"},
+			check: func(c Config) bool { return c.Address == "127.0.0.1:9003" && c.LogLevel == "debug" },
+		},
+		{
+			name:          "invalid duration",
+			args:          []string{"-ttl", "soon"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid environment variable",
+			env:           map[string]string{"P2P_SEED": "abc"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown flag",
+			args:          []string{"-port", "8080"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown file key",
+			args:          []string{"-config", unknownKey},
+			expectedError: true,
+		},
+		{
+			name:          "unknown file type",
+			args:          []string{"-config", unknownType},
+			expectedError: true,
+		},
+		{
+			name:          "missing file",
+			args:          []string{"-config", filepath.Join(t.TempDir(), "missing.json")},
+			expectedError: true,
+		},
+		{
+			name:          "validation error",
+			args:          []string{"-placement", "capacity"},
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				return testCase.env[key]
+			}
+
+			config, err := Load(testCase.args, getenv)
+
+			if testCase.expectedError {
+				if err == nil {
+					t.Errorf("expected an error, but got %+v", config)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected nil, but got %s", err.Error())
+			}
+
+			if !testCase.check(config) {
+				t.Errorf("unexpected config %+v", config)
+			}
+		})
+	}
+}
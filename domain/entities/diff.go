@@ -0,0 +1,31 @@
+package entities
+
+// ParentChange: a peer attached to a different parent. Parent is zero, if the peer is a root
+type ParentChange struct {
+	Id        int
+	OldParent int
+	NewParent int
+}
+
+// RootChange: a peer which became a root or stopped being one
+type RootChange struct {
+	Id   int
+	Root bool // whether the peer is a root afterwards
+}
+
+// DepthChange: a peer which moved to a different level of its tree
+type DepthChange struct {
+	Id       int
+	OldDepth int
+	NewDepth int
+}
+
+// Diff: the differences between two topologies. Every list is sorted by id.
+// Changes are only listed for the peers which are in both topologies
+type Diff struct {
+	Added   []int
+	Removed []int
+	Parents []ParentChange
+	Roots   []RootChange
+	Depths  []DepthChange
+}
@@ -0,0 +1,76 @@
+package entities
+
+import "fmt"
+
+// Error: a domain error with a machine readable code.
+// Errors with the same code match each other with errors.Is, whatever the message is
+type Error struct {
+	Code    string
+	Message string
+}
+
+// NewError: creates a domain error
+func NewError(code string, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error: returns the message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is: reports whether the target is a domain error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Code == e.Code
+}
+
+// Errorf: returns an error with the same code and the formatted message
+func (e *Error) Errorf(format string, args ...interface{}) *Error {
+	return NewError(e.Code, fmt.Sprintf(format, args...))
+}
+
+var (
+	// ErrInvalidID: the id is not a positive integer
+	ErrInvalidID = NewError("invalid_id", "id must be a positive integer")
+
+	// ErrInvalidCapacity: the capacity is negative
+	ErrInvalidCapacity = NewError("invalid_capacity", "capacity must be none negative")
+
+	// ErrDuplicateID: the id is already in the network
+	ErrDuplicateID = NewError("duplicate_id", "id already reserved")
+
+	// ErrNodeNotFound: the id is not in the network
+	ErrNodeNotFound = NewError("node_not_found", "node not found")
+
+	// ErrAlreadyFailed: the node already crashed and is not repaired yet
+	ErrAlreadyFailed = NewError("already_failed", "node already failed")
+
+	// ErrInvalidTopology: the peers do not form a valid topology
+	ErrInvalidTopology = NewError("invalid_topology", "invalid topology")
+
+	// ErrInvalidOperation: the operation of a transaction is not known
+	ErrInvalidOperation = NewError("invalid_operation", "invalid operation")
+
+	// ErrNothingToUndo: there is no operation in the history to revert
+	ErrNothingToUndo = NewError("nothing_to_undo", "nothing to undo")
+
+	// ErrNothingToRedo: there is no reverted operation to apply again
+	ErrNothingToRedo = NewError("nothing_to_redo", "nothing to redo")
+
+	// ErrVersionNotFound: the version is not kept in the history
+	ErrVersionNotFound = NewError("version_not_found", "version not found")
+
+	// ErrDepthExceeded: the node cannot join without going deeper than the max depth of the network
+	ErrDepthExceeded = NewError("depth_exceeded", "max depth exceeded")
+
+	// ErrNoCapacity: no peer has free capacity for the node, and only sources can start a tree
+	ErrNoCapacity = NewError("no_capacity", "no free capacity")
+
+	// ErrUnsupported: the operation is not supported by the topology of the network
+	ErrUnsupported = NewError("unsupported", "operation not supported by the topology")
+)
@@ -0,0 +1,23 @@
+package entities
+
+// GraphNode: a peer of the network graph
+type GraphNode struct {
+	Id       int
+	Capacity int // max capacity
+	Used     int // number of children
+	Depth    int // roots have zero depth
+	Source   bool
+}
+
+// GraphEdge: a link from a parent to its child
+type GraphEdge struct {
+	Parent int
+	Child  int
+}
+
+// Graph: the network as nodes and edges, for the topologies which are not only trees.
+// Nodes are sorted by depth then id, and edges by parent then child
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
@@ -1,6 +1,34 @@
 package entities
 
+import "math"
+
 type Node struct {
 	Id       int
 	Capacity int
+	Location
+}
+
+// Admission: how a joining node is admitted to the network
+type Admission int
+
+const (
+	Admitted Admission = iota // the node is placed in the network
+	Queued                    // the node waits until a peer has free capacity for it
+)
+
+// Location: where a peer is. Peers without a region are in no region
+type Location struct {
+	Region string
+	X      float64
+	Y      float64
+}
+
+// SameRegion: reports whether both locations are in the same region
+func (l Location) SameRegion(other Location) bool {
+	return l.Region != "" && l.Region == other.Region
+}
+
+// Distance: returns the euclidean distance between the coordinates of the locations
+func (l Location) Distance(other Location) float64 {
+	return math.Hypot(l.X-other.X, l.Y-other.Y)
 }
@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// QueuedPeer: a peer waiting in the queue for free capacity to join the network
+type QueuedPeer struct {
+	Id       int
+	Capacity int       // max capacity
+	Peers    int       // number of peers waiting with it in its subtree, itself included
+	Since    time.Time // waiting since
+}
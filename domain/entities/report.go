@@ -0,0 +1,87 @@
+package entities
+
+import "time"
+
+// Reassignment: describes how a peer is attached to the network after another peer left
+type Reassignment struct {
+	Id        int
+	OldParent int // zero, if the peer was a root
+	NewParent int // zero, if the peer became a root
+	Root      bool
+	Stripe    *int // stripe the peer is attached in, nil outside the stripes topology
+}
+
+// LeaveReport: describes the changes made to the network when a peer left
+type LeaveReport struct {
+	Id            int
+	Reassignments []Reassignment
+	Dropped       []int // peers which left with the peer, since no tree of a source could take them
+	Disruption    Disruption
+}
+
+// Disruption: the cost of a leave for the peers which stay in the network
+type Disruption struct {
+	Id            int // the peer which left
+	ChangedParent int // peers attached to a different parent
+	Rehomed       int // subtrees re attached to the network beneath a new parent
+	DepthChange   int // max depth of the network after the leave, minus the one before
+}
+
+// DisruptionStats: running totals of the disruptions, with the records of the latest leaves
+type DisruptionStats struct {
+	Leaves        int
+	ChangedParent int
+	Rehomed       int
+	DepthChange   int
+	Records       []Disruption // oldest first
+}
+
+// FailureReport: describes the peers disconnected from the network when a peer crashed
+type FailureReport struct {
+	Id           int
+	Disconnected int       // number of peers in the subtree of the crashed peer
+	FailedAt     time.Time // the subtree is disconnected from this time
+	RepairAt     time.Time // until this time
+}
+
+// Stats: summary of the network
+type Stats struct {
+	Peers        int
+	Trees        int
+	MaxDepth     int // depth of the deepest peer, roots have zero depth
+	FreeCapacity int // sum of the free capacity of every peer
+	Reorders     int // number of times a peer swapped with its parent
+	Rehomed      int // number of subtrees re attached to the network when a peer left
+
+	// forwarding load of the peers when the content is split into stripes. zero otherwise
+	MaxForwarding  int     // most children of a peer, summed over the stripes
+	MeanForwarding float64 // children per peer, summed over the stripes
+}
+
+// StripeStats: summary of the trees of a stripe
+type StripeStats struct {
+	Stripe       int
+	Peers        int
+	Trees        int
+	MaxDepth     int
+	Interior     int // peers with children in the stripe
+	FreeCapacity int
+}
+
+// TreeLocality: how many edges of a tree cross between the regions
+type TreeLocality struct {
+	Root        int
+	Edges       int
+	CrossRegion int     // edges between peers which are not in the same region
+	Distance    float64 // sum of the distances of the cross region edges
+}
+
+// Reputation: the session history of a peer id, kept after the peer leaves the network
+type Reputation struct {
+	Id             int
+	Joins          int           // number of times the peer was placed in the network
+	Failures       int           // number of times the peer crashed
+	AverageSession time.Duration // mean length of the sessions, the current one included
+	Online         bool          // the peer is in the network
+	Stable         bool          // the peer is preferred as an interior peer
+}
@@ -0,0 +1,10 @@
+package entities
+
+// PeerRecord: a peer with its parent, used to save and restore the topology of the network
+type PeerRecord struct {
+	Id       int
+	Capacity int  // max capacity
+	Parent   int  // zero, if the peer is a root
+	Source   bool // sources are always roots
+	Location Location
+}
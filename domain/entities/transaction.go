@@ -0,0 +1,24 @@
+package entities
+
+// operation types of a transaction
+const (
+	OperationJoin     = "join"
+	OperationLeave    = "leave"
+	OperationCapacity = "capacity"
+)
+
+// Operation: a single change of a transaction. Capacity is used by join and capacity operations,
+// the location only by join operations
+type Operation struct {
+	Type     string
+	Id       int
+	Capacity int
+	Location Location
+}
+
+// TransactionResult: the network after every operation of a transaction is applied
+type TransactionResult struct {
+	Trace     []string
+	Stats     Stats
+	Committed bool // false, if the transaction is a dry run
+}
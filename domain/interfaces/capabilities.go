@@ -0,0 +1,48 @@
+package interfaces
+
+import "p2p-network-simulator/domain/entities"
+
+// Sources: a network where the sources are the roots of the trees
+type Sources interface {
+	AddSource(node entities.Node) error
+}
+
+// Failures: a network where peers can crash, and are repaired after a detection delay
+type Failures interface {
+	Fail(id int) (entities.FailureReport, error)
+}
+
+// Queue: a network where joining nodes can wait for free capacity
+type Queue interface {
+	Queue() []entities.QueuedPeer
+}
+
+// Stripes: a network where the content is split into stripes
+type Stripes interface {
+	Stripes() []entities.StripeStats
+}
+
+// Locality: a network which reports the edges between the regions of its trees
+type Locality interface {
+	Locality() []entities.TreeLocality
+}
+
+// Reputation: a network which keeps the session history of its peers
+type Reputation interface {
+	Reputation() []entities.Reputation
+}
+
+// History: a network which keeps the versions of its topology, so operations can be undone
+type History interface {
+	Undo() (int, error)
+	Redo() (int, error)
+	Version() int
+	TraceVersion(version int) ([]string, error)
+	RenderVersion(version int) ([]string, error)
+	Diff(from int, to int) (entities.Diff, error)
+}
+
+// Transactions: a network which applies a list of operations at once
+type Transactions interface {
+	Transaction(operations []entities.Operation, dryRun bool) (entities.TransactionResult, error)
+}
@@ -2,8 +2,17 @@ package interfaces
 
 import "p2p-network-simulator/domain/entities"
 
+// P2PNetwork: the operations every topology supports
 type P2PNetwork interface {
-	Join(node entities.Node) error
-	Leave(id int) error
+	Join(node entities.Node) (entities.Admission, error)
+	Leave(id int) (entities.LeaveReport, error)
 	Trace() []string
+	Render() []string
+	Graph() entities.Graph
+	Stats() entities.Stats
+	Disruption() entities.DisruptionStats
+	Merge() int
+	Heartbeat(id int) error
+	Evict() []entities.LeaveReport
+	SetCapacity(id int, capacity int) error
 }
@@ -0,0 +1,9 @@
+package interfaces
+
+import "p2p-network-simulator/domain/entities"
+
+// Snapshots: a network which can be saved as a forest, and restored from one
+type Snapshots interface {
+	Snapshot() []entities.PeerRecord
+	Restore(records []entities.PeerRecord) error
+}
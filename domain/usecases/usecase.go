@@ -15,14 +15,100 @@ func NewSimulator(network interfaces.P2PNetwork) Simulator {
 	}
 }
 
-func (s Simulator) Join(node entities.Node) error {
+func (s Simulator) Join(node entities.Node) (entities.Admission, error) {
 	return s.network.Join(node)
 }
 
-func (s Simulator) Leave(id int) error {
+func (s Simulator) Leave(id int) (entities.LeaveReport, error) {
 	return s.network.Leave(id)
 }
 
 func (s Simulator) Trace() []string {
 	return s.network.Trace()
 }
+
+func (s Simulator) Render() []string {
+	return s.network.Render()
+}
+
+func (s Simulator) Graph() entities.Graph {
+	return s.network.Graph()
+}
+
+func (s Simulator) Stats() entities.Stats {
+	return s.network.Stats()
+}
+
+func (s Simulator) Disruption() entities.DisruptionStats {
+	return s.network.Disruption()
+}
+
+func (s Simulator) Merge() int {
+	return s.network.Merge()
+}
+
+func (s Simulator) Heartbeat(id int) error {
+	return s.network.Heartbeat(id)
+}
+
+func (s Simulator) Evict() []entities.LeaveReport {
+	return s.network.Evict()
+}
+
+func (s Simulator) SetCapacity(id int, capacity int) error {
+	return s.network.SetCapacity(id, capacity)
+}
+
+// Sources: returns the network, if its topology has sources
+func (s Simulator) Sources() (interfaces.Sources, bool) {
+	sources, ok := s.network.(interfaces.Sources)
+	return sources, ok
+}
+
+// Failures: returns the network, if its peers can crash
+func (s Simulator) Failures() (interfaces.Failures, bool) {
+	failures, ok := s.network.(interfaces.Failures)
+	return failures, ok
+}
+
+// Queue: returns the network, if joining nodes can wait for free capacity in it
+func (s Simulator) Queue() (interfaces.Queue, bool) {
+	queue, ok := s.network.(interfaces.Queue)
+	return queue, ok
+}
+
+// Stripes: returns the network, if its content is split into stripes
+func (s Simulator) Stripes() (interfaces.Stripes, bool) {
+	stripes, ok := s.network.(interfaces.Stripes)
+	return stripes, ok
+}
+
+// Locality: returns the network, if it reports the regions of its trees
+func (s Simulator) Locality() (interfaces.Locality, bool) {
+	locality, ok := s.network.(interfaces.Locality)
+	return locality, ok
+}
+
+// Reputation: returns the network, if it keeps the sessions of its peers
+func (s Simulator) Reputation() (interfaces.Reputation, bool) {
+	reputation, ok := s.network.(interfaces.Reputation)
+	return reputation, ok
+}
+
+// History: returns the network, if it keeps the versions of its topology
+func (s Simulator) History() (interfaces.History, bool) {
+	history, ok := s.network.(interfaces.History)
+	return history, ok
+}
+
+// Transactions: returns the network, if it applies transactions
+func (s Simulator) Transactions() (interfaces.Transactions, bool) {
+	transactions, ok := s.network.(interfaces.Transactions)
+	return transactions, ok
+}
+
+// Snapshots: returns the network, if it can be saved and restored
+func (s Simulator) Snapshots() (interfaces.Snapshots, bool) {
+	snapshots, ok := s.network.(interfaces.Snapshots)
+	return snapshots, ok
+}
@@ -2,4 +2,7 @@ module p2p-network-simulator
 
 go 1.18
 
-require github.com/gorilla/mux v1.8.0
+require (
+	github.com/gorilla/mux v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// errorStatus: maps the domain errors to the status codes.
+// Returns the given fallback status for the other errors
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, entities.ErrInvalidID),
+		errors.Is(err, entities.ErrInvalidCapacity),
+		errors.Is(err, entities.ErrInvalidTopology),
+		errors.Is(err, entities.ErrInvalidOperation):
+		return http.StatusBadRequest
+
+	case errors.Is(err, entities.ErrNodeNotFound),
+		errors.Is(err, entities.ErrVersionNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, entities.ErrDuplicateID),
+		errors.Is(err, entities.ErrAlreadyFailed),
+		errors.Is(err, entities.ErrNothingToUndo),
+		errors.Is(err, entities.ErrNothingToRedo),
+		errors.Is(err, entities.ErrDepthExceeded),
+		errors.Is(err, entities.ErrNoCapacity):
+		return http.StatusConflict
+
+	case errors.Is(err, entities.ErrUnsupported):
+		return http.StatusNotImplemented
+	}
+
+	return fallback
+}
+
+// errorCode: returns the machine readable code of the error.
+// The errors which are not domain errors get the code of the status (bad_request)
+func errorCode(err error, status int) string {
+	var domainError *entities.Error
+
+	if errors.As(err, &domainError) {
+		return domainError.Code
+	}
+
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestErrorStatus(t *testing.T) {
+	testTable := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid id",
+			err:            entities.ErrInvalidID,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_id",
+		},
+		{
+			name:           "invalid topology",
+			err:            entities.ErrInvalidTopology.Errorf("cannot locate parent id 1 of node 2"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_topology",
+		},
+		{
+			name:           "node not found",
+			err:            entities.ErrNodeNotFound.Errorf("cannot locate id %d node", 2),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "node_not_found",
+		},
+		{
+			name:           "wrapped duplicate id",
+			err:            fmt.Errorf("join: %w", entities.ErrDuplicateID.Errorf("id %d already reserved", 1)),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "duplicate_id",
+		},
+		{
+			name:           "already failed",
+			err:            entities.ErrAlreadyFailed,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "already_failed",
+		},
+		{
+			name:           "depth exceeded",
+			err:            entities.ErrDepthExceeded.Errorf("node %d cannot join within the max depth %d", 3, 1),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "depth_exceeded",
+		},
+		{
+			name:           "no capacity",
+			err:            entities.ErrNoCapacity,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "no_capacity",
+		},
+		{
+			name:           "unsupported",
+			err:            entities.ErrUnsupported.Errorf("undo is not supported by the mesh topology"),
+			expectedStatus: http.StatusNotImplemented,
+			expectedCode:   "unsupported",
+		},
+		{
+			name:           "not a domain error",
+			err:            errors.New("unexpected end of JSON input"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "unprocessable_entity",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			status := errorStatus(testCase.err, http.StatusUnprocessableEntity)
+
+			if status != testCase.expectedStatus {
+				t.Errorf("expected %d, but got %d", testCase.expectedStatus, status)
+			}
+
+			code := errorCode(testCase.err, status)
+
+			if code != testCase.expectedCode {
+				t.Errorf("expected %s, but got %s", testCase.expectedCode, code)
+			}
+		})
+	}
+}
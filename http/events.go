@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"p2p-network-simulator/logging"
+)
+
+// Event: a change of the network, pushed to the subscribers
+type Event struct {
+	Type string      `json:"type"`
+	Id   int         `json:"id"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// broker: fans out events to the subscribers.
+// A slow subscriber misses events instead of blocking the publisher
+type broker struct {
+	subscribers map[chan Event]struct{}
+
+	// using mutex to prevent from the concurrent accesses to the subscribers
+	lock sync.Mutex
+}
+
+// newBroker: creates a broker without subscribers
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe: returns a channel which receives the published events
+func (b *broker) Subscribe() chan Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	channel := make(chan Event, 16)
+	b.subscribers[channel] = struct{}{}
+
+	return channel
+}
+
+// Unsubscribe: stops sending events to the given channel and closes it
+func (b *broker) Unsubscribe(channel chan Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.subscribers[channel]; !ok {
+		return
+	}
+
+	delete(b.subscribers, channel)
+	close(channel)
+}
+
+// Publish: sends the given event to every subscriber
+func (b *broker) Publish(event Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for channel := range b.subscribers {
+		select {
+		case channel <- event:
+		default:
+		}
+	}
+}
+
+// publish: emits a change of the network for the given node
+func (hdl handler) publish(eventType string, id int, data interface{}) {
+	hdl.events.Publish(Event{
+		Type: eventType,
+		Id:   id,
+		Time: time.Now(),
+		Data: data,
+	})
+}
+
+/*
+Events: controller for stream the changes of the network as server-sent events.
+
+	event: join
+	data: {"type":"join","id":1,"time":"2022-08-01T10:00:00Z","data":{"id":1,"capacity":2}}
+
+The stream ends when the client disconnects or the write timeout of the server elapses,
+EventSource clients reconnect by themselves
+*/
+func (hdl handler) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		err := fmt.Errorf("streaming is not supported")
+
+		hdl.log(r).Error(err.Error(), logging.F("status", http.StatusInternalServerError))
+		handleError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	channel := hdl.events.Subscribe()
+	defer hdl.events.Unsubscribe(channel)
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// lets the client know the stream is open, before the first event
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	hdl.log(r).Debug("event stream opened")
+
+	for {
+		select {
+		case <-r.Context().Done():
+			hdl.log(r).Debug("event stream closed")
+			return
+
+		case event := <-channel:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				hdl.log(r).Error(err.Error(), logging.F("event", event.Type))
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/logging"
+)
+
+func TestBroker(t *testing.T) {
+	b := newBroker()
+
+	first := b.Subscribe()
+	second := b.Subscribe()
+
+	b.Publish(Event{Type: "evict", Id: 1})
+
+	for _, channel := range []chan Event{first, second} {
+		event := <-channel
+
+		if event.Type != "evict" || event.Id != 1 {
+			t.Errorf("expected evict 1, but got %s %d", event.Type, event.Id)
+		}
+	}
+
+	b.Unsubscribe(first)
+
+	// unsubscribed channels are closed
+	_, ok := <-first
+	if ok {
+		t.Errorf("expected closed channel")
+	}
+
+	// unsubscribe twice does not panic
+	b.Unsubscribe(first)
+
+	// a slow subscriber does not block the publisher
+	for i := 0; i < 100; i++ {
+		b.Publish(Event{Type: "evict", Id: i})
+	}
+
+	if len(second) != cap(second) {
+		t.Errorf("expected %d, but got %d", cap(second), len(second))
+	}
+}
+
+func TestEvents(t *testing.T) {
+	hdl := newHandler(config.Default())
+	hdl.logger = logging.Discard()
+
+	server := httptest.NewServer(initRouter(hdl))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("content-type") != "text/event-stream" {
+		t.Errorf("expected %v, but got %v", "text/event-stream", res.Header.Get("content-type"))
+	}
+
+	reader := bufio.NewReader(res.Body)
+
+	// wait for the stream to be open, so the subscription is in place before the join
+	line, err := reader.ReadString('\n')
+	if err != nil || line != ": connected\n" {
+		t.Fatalf("expected the stream to be open, but got %q %v", line, err)
+	}
+
+	_, err = http.Post(server.URL+"/join", "application/json", strings.NewReader(`{"id":1,"capacity":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"\n",
+		"event: join\n",
+	}
+
+	for _, e := range expected {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != e {
+			t.Errorf("expected %q, but got %q", e, line)
+		}
+	}
+
+	line, _ = reader.ReadString('\n')
+
+	if !strings.HasPrefix(line, `data: {"type":"join","id":1,`) || !strings.HasSuffix(line, `"data":{"id":1,"capacity":2}}`+"\n") {
+		t.Errorf("expected the join event, but got %q", line)
+	}
+}
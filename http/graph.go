@@ -0,0 +1,84 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// GraphNode: a peer of the network graph. Roots have zero depth
+type GraphNode struct {
+	Id       int  `json:"id"`
+	Capacity int  `json:"capacity"`
+	Used     int  `json:"used"`
+	Depth    int  `json:"depth"`
+	Source   bool `json:"source,omitempty"`
+}
+
+// GraphEdge: a link from a parent to its child
+type GraphEdge struct {
+	Parent int `json:"parent"`
+	Child  int `json:"child"`
+}
+
+// Graph: the network as nodes and edges, which also represents peers with more than one parent
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+func newGraph(graph entities.Graph) Graph {
+	result := Graph{
+		Nodes: make([]GraphNode, 0, len(graph.Nodes)),
+		Edges: make([]GraphEdge, 0, len(graph.Edges)),
+	}
+
+	for _, n := range graph.Nodes {
+		result.Nodes = append(result.Nodes, GraphNode{
+			Id:       n.Id,
+			Capacity: n.Capacity,
+			Used:     n.Used,
+			Depth:    n.Depth,
+			Source:   n.Source,
+		})
+	}
+
+	for _, e := range graph.Edges {
+		result.Edges = append(result.Edges, GraphEdge{Parent: e.Parent, Child: e.Child})
+	}
+
+	return result
+}
+
+/*
+dot: writes the graph in the Graphviz DOT language, sources are drawn with a double circle.
+
+	digraph network {
+	  1 [label="1 (2/2)"];
+	  2 [label="2 (0/1)"];
+	  1 -> 2;
+	}
+*/
+func dot(graph entities.Graph) string {
+	var builder strings.Builder
+
+	builder.WriteString("digraph network {\n")
+
+	for _, n := range graph.Nodes {
+		shape := ""
+		if n.Source {
+			shape = ", shape=doublecircle"
+		}
+
+		builder.WriteString(fmt.Sprintf("  %d [label=\"%d (%d/%d)\"%s];\n", n.Id, n.Id, n.Used, n.Capacity, shape))
+	}
+
+	for _, e := range graph.Edges {
+		builder.WriteString(fmt.Sprintf("  %d -> %d;\n", e.Parent, e.Child))
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
@@ -1,89 +1,652 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
-	"strconv"
+	"os"
+	"strings"
+	"time"
 
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/domain/entities"
 	"p2p-network-simulator/domain/usecases"
+	"p2p-network-simulator/logging"
 	"p2p-network-simulator/storage"
-
-	"github.com/gorilla/mux"
+	"p2p-network-simulator/storage/treap"
 )
 
 type handler struct {
 	usecase usecases.Simulator
+
+	// topology of the network, named in the errors of the features it does not support
+	topology string
+
+	// publishes the changes of the network
+	events *broker
+
+	// request metrics of the routes
+	metrics *metrics
+
+	// structured logger, requests use a derived logger carrying the request id
+	logger *logging.Logger
+
+	// max size of a request body in bytes
+	maxBodySize int64
 }
 
-func newHandler() handler {
-	network := storage.NewP2PNetwork()
+func newHandler(cfg config.Config) handler {
+	tieBreak, _ := treap.ParseTieBreak(cfg.Placement)
+	queue, _ := storage.ParseQueuePolicy(cfg.Queue)
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+
+	logger := logging.New(os.Stdout, level, cfg.LogFormat)
+
+	events := newBroker()
+
+	options := storage.Options{
+		TieBreak:       tieBreak,
+		Seed:           cfg.Seed,
+		AutoMerge:      cfg.AutoMerge,
+		Promote:        cfg.Promote,
+		DetectionDelay: cfg.DetectionDelay,
+		TTL:            cfg.TTL,
+		HistorySize:    cfg.HistorySize,
+		MaxDepth:       cfg.MaxDepth,
+		Locality:       cfg.Locality,
+		StableAfter:    cfg.StableAfter,
+		SourcesOnly:    cfg.SourcesOnly,
+		Queue:          queue,
+		Parents:        cfg.Parents,
+		Stripes:        cfg.Stripes,
+		Logger:         logger.With(logging.F("component", "network")),
+		OnRepair: func(report entities.LeaveReport) {
+			events.Publish(Event{Type: "repair", Id: report.Id, Time: time.Now(), Data: newLeaveReport(report)})
+		},
+	}
+
+	network := storage.NewP2PNetworkWithOptions(options)
+
+	switch cfg.Topology {
+	case "mesh":
+		network = storage.NewMeshNetwork(options)
+	case "stripes":
+		network = storage.NewStripedNetwork(options)
+	}
 
 	return handler{
-		usecase: usecases.NewSimulator(network),
+		usecase:     usecases.NewSimulator(network),
+		topology:    cfg.Topology,
+		events:      events,
+		metrics:     newMetrics(),
+		logger:      logger,
+		maxBodySize: cfg.MaxBodySize,
 	}
 }
 
 // Join: controller for join the network
 func (hdl handler) Join(w http.ResponseWriter, r *http.Request) {
+	// limit the size of the request body
+	if hdl.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, hdl.maxBodySize)
+	}
+
 	// decode request body
 	node, err := decodeRequest(r)
 	if err != nil {
-		log.Printf("error:%s\n", err.Error())
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
 
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, err, status)
 		return
 	}
 
-	err = hdl.usecase.Join(node)
+	admission, err := hdl.usecase.Join(node)
 	if err != nil {
-		log.Printf("error:%s\n", err.Error())
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("node", node.Id), logging.F("status", status))
 
-		handleError(w, err, http.StatusUnprocessableEntity)
+		handleError(w, err, status)
 		return
 	}
 
-	log.Printf("trace:node %d<%d> join the network\n", node.Id, node.Capacity)
+	// the node joins once a peer has free capacity for it
+	if admission == entities.Queued {
+		hdl.log(r).Info("node is waiting in the queue", logging.F("node", node.Id), logging.F("capacity", node.Capacity))
+		hdl.publish("queue", node.Id, newNode(node))
+		handle(w, "waiting for free capacity", node.Id, http.StatusAccepted)
+		return
+	}
+
+	hdl.log(r).Info("node joined the network", logging.F("node", node.Id), logging.F("capacity", node.Capacity))
+	hdl.publish("join", node.Id, newNode(node))
 	handle(w, "successfully joined", node.Id, http.StatusCreated)
 }
 
-// Join: controller for leave the network
-func (hdl handler) Leave(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+// AddSource: controller for add a source to the network
+func (hdl handler) AddSource(w http.ResponseWriter, r *http.Request) {
+	sources, ok := hdl.usecase.Sources()
+	if !ok {
+		hdl.unsupported(w, r, "sources are")
+		return
+	}
 
-	// retrive id from the request
-	id, err := strconv.Atoi(vars["id"])
+	// limit the size of the request body
+	if hdl.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, hdl.maxBodySize)
+	}
+
+	// decode request body
+	node, err := decodeRequest(r)
 	if err != nil {
-		log.Printf("error:%s\n", err.Error())
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
 
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, err, status)
 		return
 	}
 
-	if id < 1 {
-		err = errors.New("id must be a positive integer")
-		log.Printf("error:%s\n", err.Error())
+	err = sources.AddSource(node)
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("node", node.Id), logging.F("status", status))
 
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, err, status)
 		return
 	}
 
-	err = hdl.usecase.Leave(id)
+	hdl.log(r).Info("source added to the network", logging.F("node", node.Id), logging.F("capacity", node.Capacity))
+	hdl.publish("source", node.Id, newNode(node))
+	handle(w, "source added", node.Id, http.StatusCreated)
+}
+
+// Join: controller for leave the network
+func (hdl handler) Leave(w http.ResponseWriter, r *http.Request) {
+	// retrive id from the request
+	id, err := decodeId(r)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	report, err := hdl.usecase.Leave(id)
 	if err != nil {
-		log.Printf("error:%s\n", err.Error())
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("node", id), logging.F("status", status))
 
-		handleError(w, err, http.StatusUnprocessableEntity)
+		handleError(w, err, status)
 		return
 	}
 
-	log.Printf("trace:node %d leave the network\n", id)
-	handle(w, "successfully left", id, http.StatusAccepted)
+	hdl.log(r).Info("node left the network", logging.F("node", id), logging.F("reassigned", len(report.Reassignments)), logging.F("rehomed", report.Disruption.Rehomed))
+	hdl.publish("leave", id, newLeaveReport(report))
+
+	handle(w, "successfully left", newLeaveReport(report), http.StatusAccepted)
 }
 
 // Join: controller for get trace of the network
 func (hdl handler) Trace(w http.ResponseWriter, r *http.Request) {
-	trace := hdl.usecase.Trace()
+	format := r.URL.Query().Get("format")
+
+	if format != "" && format != "json" && format != "text" && format != "graph" && format != "dot" {
+		err := fmt.Errorf("unknown trace format %q", format)
+
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	// the topology at a version of the history, or the current one
+	version, err := decodeVersion(r, "version")
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
 
-	log.Println("trace:network trace sent")
+		handleError(w, err, status)
+		return
+	}
+
+	if format == "text" {
+		hdl.traceText(w, r, version)
+		return
+	}
+
+	if format == "graph" || format == "dot" {
+		hdl.traceGraph(w, r, version, format)
+		return
+	}
+
+	var trace []string
+
+	if version < 0 {
+		trace = hdl.usecase.Trace()
+	} else {
+		history, ok := hdl.usecase.History()
+		if !ok {
+			hdl.unsupported(w, r, "versions are")
+			return
+		}
+
+		trace, err = history.TraceVersion(version)
+		if err != nil {
+			status := errorStatus(err, http.StatusUnprocessableEntity)
+			hdl.log(r).Error(err.Error(), logging.F("version", version), logging.F("status", status))
+
+			handleError(w, err, status)
+			return
+		}
+	}
+
+	hdl.log(r).Debug("network trace sent", logging.F("trees", len(trace)))
 	handle(w, "trace received", trace, http.StatusOK)
 }
+
+// traceText: writes the trees of the network drawn like the tree command, separated by an empty line
+func (hdl handler) traceText(w http.ResponseWriter, r *http.Request, version int) {
+	var drawings []string
+
+	if version < 0 {
+		drawings = hdl.usecase.Render()
+	} else {
+		history, ok := hdl.usecase.History()
+		if !ok {
+			hdl.unsupported(w, r, "versions are")
+			return
+		}
+
+		var err error
+
+		drawings, err = history.RenderVersion(version)
+		if err != nil {
+			status := errorStatus(err, http.StatusUnprocessableEntity)
+			hdl.log(r).Error(err.Error(), logging.F("version", version), logging.F("status", status))
+
+			handleError(w, err, status)
+			return
+		}
+	}
+
+	hdl.log(r).Debug("network trace sent", logging.F("trees", len(drawings)), logging.F("format", "text"))
+
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(strings.Join(drawings, "\n")))
+}
+
+// traceGraph: writes the current network as nodes and edges in JSON, or in the Graphviz DOT language
+func (hdl handler) traceGraph(w http.ResponseWriter, r *http.Request, version int, format string) {
+	// the history keeps trees only, the graph is drawn for the current network
+	if version >= 0 {
+		err := fmt.Errorf("trace format %q does not support a version", format)
+
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("version", version), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	graph := hdl.usecase.Graph()
+
+	hdl.log(r).Debug("network trace sent", logging.F("peers", len(graph.Nodes)), logging.F("format", format))
+
+	if format == "graph" {
+		handle(w, "trace received", newGraph(graph), http.StatusOK)
+		return
+	}
+
+	w.Header().Set("content-type", "text/vnd.graphviz; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(dot(graph)))
+}
+
+// Merge: controller for collapse the trees of the network
+func (hdl handler) Merge(w http.ResponseWriter, r *http.Request) {
+	merged := hdl.usecase.Merge()
+
+	hdl.log(r).Info("network merged", logging.F("merged", merged))
+
+	if merged > 0 {
+		hdl.publish("merge", 0, merged)
+	}
+
+	handle(w, "network merged", merged, http.StatusOK)
+}
+
+// Diff: controller for get the differences between two versions of the topology.
+// The current version is used, if to is not given
+func (hdl handler) Diff(w http.ResponseWriter, r *http.Request) {
+	history, ok := hdl.usecase.History()
+	if !ok {
+		hdl.unsupported(w, r, "versions are")
+		return
+	}
+
+	from, err := decodeVersion(r, "from")
+	if err == nil && from < 0 {
+		err = errors.New("from is required")
+	}
+
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	to, err := decodeVersion(r, "to")
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	if to < 0 {
+		to = history.Version()
+	}
+
+	diff, err := history.Diff(from, to)
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("from", from), logging.F("to", to), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.log(r).Debug("diff sent", logging.F("from", from), logging.F("to", to))
+	handle(w, "diff received", newDiff(diff), http.StatusOK)
+}
+
+// Transaction: controller for apply a list of operations at once.
+// A dry run returns the resulting network without changing it
+func (hdl handler) Transaction(w http.ResponseWriter, r *http.Request) {
+	transactions, ok := hdl.usecase.Transactions()
+	if !ok {
+		hdl.unsupported(w, r, "transactions are")
+		return
+	}
+
+	// limit the size of the request body
+	if hdl.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, hdl.maxBodySize)
+	}
+
+	dryRun, err := decodeDryRun(r)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	operations, err := decodeTransaction(r)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	result, err := transactions.Transaction(operations, dryRun)
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("operations", len(operations)), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	if dryRun {
+		hdl.log(r).Info("transaction evaluated", logging.F("operations", len(operations)))
+		handle(w, "transaction evaluated", newTransactionResult(result), http.StatusOK)
+
+		return
+	}
+
+	hdl.log(r).Info("transaction committed", logging.F("operations", len(operations)))
+	hdl.publish("transaction", 0, len(operations))
+
+	handle(w, "transaction committed", newTransactionResult(result), http.StatusOK)
+}
+
+// Undo: controller for revert the last operation which changed the topology
+func (hdl handler) Undo(w http.ResponseWriter, r *http.Request) {
+	history, ok := hdl.usecase.History()
+	if !ok {
+		hdl.unsupported(w, r, "undo is")
+		return
+	}
+
+	version, err := history.Undo()
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.log(r).Info("operation undone", logging.F("version", version))
+	hdl.publish("undo", 0, version)
+
+	handle(w, "successfully undone", version, http.StatusOK)
+}
+
+// Redo: controller for apply the last reverted operation again
+func (hdl handler) Redo(w http.ResponseWriter, r *http.Request) {
+	history, ok := hdl.usecase.History()
+	if !ok {
+		hdl.unsupported(w, r, "redo is")
+		return
+	}
+
+	version, err := history.Redo()
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.log(r).Info("operation redone", logging.F("version", version))
+	hdl.publish("redo", 0, version)
+
+	handle(w, "successfully redone", version, http.StatusOK)
+}
+
+// Fail: controller for crash a node without leaving the network
+func (hdl handler) Fail(w http.ResponseWriter, r *http.Request) {
+	failures, ok := hdl.usecase.Failures()
+	if !ok {
+		hdl.unsupported(w, r, "crashes are")
+		return
+	}
+
+	// retrive id from the request
+	id, err := decodeId(r)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	report, err := failures.Fail(id)
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("node", id), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.log(r).Info("node failed", logging.F("node", id), logging.F("disconnected", report.Disconnected))
+	hdl.publish("fail", id, newFailureReport(report))
+
+	handle(w, "successfully failed", newFailureReport(report), http.StatusAccepted)
+}
+
+// Heartbeat: controller for keep a node alive in the network
+func (hdl handler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	// retrive id from the request
+	id, err := decodeId(r)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	err = hdl.usecase.Heartbeat(id)
+	if err != nil {
+		status := errorStatus(err, http.StatusUnprocessableEntity)
+		hdl.log(r).Error(err.Error(), logging.F("node", id), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.log(r).Debug("heartbeat received", logging.F("node", id))
+	handle(w, "heartbeat received", id, http.StatusOK)
+}
+
+// Stats: controller for get the statistics of the network
+func (hdl handler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats := hdl.usecase.Stats()
+
+	response := newStats(stats)
+
+	if stripes, ok := hdl.usecase.Stripes(); ok {
+		response.Stripes = newStripes(stripes.Stripes())
+	}
+
+	hdl.log(r).Debug("network stats sent", logging.F("peers", stats.Peers))
+	handle(w, "stats received", response, http.StatusOK)
+}
+
+// Disruption: controller for get the running totals of the disruptions of the leaves, with the latest records
+func (hdl handler) Disruption(w http.ResponseWriter, r *http.Request) {
+	stats := hdl.usecase.Disruption()
+
+	hdl.log(r).Debug("disruption stats sent", logging.F("leaves", stats.Leaves))
+	handle(w, "disruption stats received", newDisruptionStats(stats), http.StatusOK)
+}
+
+// Queue: controller for get the peers waiting for free capacity, in the order they would be admitted
+func (hdl handler) Queue(w http.ResponseWriter, r *http.Request) {
+	waiting, ok := hdl.usecase.Queue()
+	if !ok {
+		hdl.unsupported(w, r, "the queue is")
+		return
+	}
+
+	queue := waiting.Queue()
+
+	hdl.log(r).Debug("queue sent", logging.F("waiting", len(queue)))
+	handle(w, "queue received", newQueue(queue, time.Now()), http.StatusOK)
+}
+
+// Locality: controller for get the edges between the regions of each tree
+func (hdl handler) Locality(w http.ResponseWriter, r *http.Request) {
+	locality, ok := hdl.usecase.Locality()
+	if !ok {
+		hdl.unsupported(w, r, "locality is")
+		return
+	}
+
+	report := locality.Locality()
+
+	hdl.log(r).Debug("locality sent", logging.F("trees", len(report)))
+	handle(w, "locality received", newLocality(report), http.StatusOK)
+}
+
+// Reputation: controller for get the session history of each peer id
+func (hdl handler) Reputation(w http.ResponseWriter, r *http.Request) {
+	sessions, ok := hdl.usecase.Reputation()
+	if !ok {
+		hdl.unsupported(w, r, "reputation is")
+		return
+	}
+
+	reputation := sessions.Reputation()
+
+	hdl.log(r).Debug("reputation sent", logging.F("peers", len(reputation)))
+	handle(w, "reputation received", newReputation(reputation), http.StatusOK)
+}
+
+// unsupported: responds that the topology of the network does not support the given feature, like "undo is"
+func (hdl handler) unsupported(w http.ResponseWriter, r *http.Request, feature string) {
+	err := entities.ErrUnsupported.Errorf("%s not supported by the %s topology", feature, hdl.topology)
+
+	status := errorStatus(err, http.StatusNotImplemented)
+	hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+	handleError(w, err, status)
+}
+
+// Metrics: controller for get the metrics of the service in Prometheus text format
+func (hdl handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	stats := hdl.usecase.Stats()
+
+	w.Header().Set("content-type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+
+	hdl.metrics.write(w)
+
+	writeMetric(w, "p2p_peers", "gauge", "Number of peers in the network.", stats.Peers)
+	writeMetric(w, "p2p_trees", "gauge", "Number of trees in the network.", stats.Trees)
+	writeMetric(w, "p2p_max_depth", "gauge", "Depth of the deepest peer in the network.", stats.MaxDepth)
+	writeMetric(w, "p2p_free_capacity", "gauge", "Sum of the free capacity of the peers.", stats.FreeCapacity)
+	writeMetric(w, "p2p_reorders_total", "counter", "Number of times a peer swapped with its parent.", stats.Reorders)
+	writeMetric(w, "p2p_rehomed_subtrees_total", "counter", "Number of subtrees re attached to the network when a peer left.", stats.Rehomed)
+}
+
+// LogLevel: controller for get the current log level
+func (hdl handler) LogLevel(w http.ResponseWriter, r *http.Request) {
+	handle(w, "log level received", hdl.logger.Level().String(), http.StatusOK)
+}
+
+// SetLogLevel: controller for change the log level at runtime
+func (hdl handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Level string `json:"level"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	level, err := logging.ParseLevel(request.Level)
+	if err != nil {
+		status := errorStatus(err, http.StatusBadRequest)
+		hdl.log(r).Error(err.Error(), logging.F("status", status))
+
+		handleError(w, err, status)
+		return
+	}
+
+	hdl.logger.SetLevel(level)
+
+	hdl.log(r).Info("log level changed", logging.F("level", level.String()))
+	handle(w, "log level changed", level.String(), http.StatusOK)
+}
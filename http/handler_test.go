@@ -2,17 +2,22 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/logging"
 
 	"github.com/gorilla/mux"
 )
 
-var h = newHandler()
+var h = newHandler(config.Default())
 
 type FakeReader int
 
@@ -31,25 +36,25 @@ func TestJoin(t *testing.T) {
 			name:               "test readall error",
 			reader:             FakeReader(0),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"error occurred while reading","error":true,"data":null}`,
+			expectedOutput:     `{"message":"error occurred while reading","error":true,"code":"bad_request","data":null}`,
 		},
 		{
 			name:               "no body passed",
 			reader:             bytes.NewReader(nil),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"unexpected end of JSON input","error":true,"data":null}`,
+			expectedOutput:     `{"message":"unexpected end of JSON input","error":true,"code":"bad_request","data":null}`,
 		},
 		{
 			name:               "id < 1",
 			reader:             bytes.NewReader([]byte(`{"id": 0}`)),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"id must be a positive integer","error":true,"data":null}`,
+			expectedOutput:     `{"message":"id must be a positive integer","error":true,"code":"invalid_id","data":null}`,
 		},
 		{
 			name:               "negative value for capacity",
 			reader:             bytes.NewReader([]byte(`{"id":1, "capacity":-1}`)),
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"capacity must be none negative","error":true,"data":null}`,
+			expectedOutput:     `{"message":"capacity must be none negative","error":true,"code":"invalid_capacity","data":null}`,
 		},
 		{
 			name:               "happy path",
@@ -60,8 +65,8 @@ func TestJoin(t *testing.T) {
 		{
 			name:               "provide node 1 twice",
 			reader:             bytes.NewReader([]byte(`{"id":1, "capacity":1}`)),
-			expectedStatusCode: http.StatusUnprocessableEntity,
-			expectedOutput:     `{"message":"id 1 already reserved","error":true,"data":null}`,
+			expectedStatusCode: http.StatusConflict,
+			expectedOutput:     `{"message":"id 1 already reserved","error":true,"code":"duplicate_id","data":null}`,
 		},
 	}
 
@@ -95,6 +100,7 @@ func TestJoin(t *testing.T) {
 func TestTrace(t *testing.T) {
 	tableTest := []struct {
 		name               string
+		query              string
 		expectedStatusCode int
 		expectedOutput     string
 	}{
@@ -103,11 +109,47 @@ func TestTrace(t *testing.T) {
 			expectedStatusCode: http.StatusOK,
 			expectedOutput:     `{"message":"trace received","error":false,"data":["1(0/1)"]}`,
 		},
+		{
+			name:               "json format",
+			query:              "?format=json",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["1(0/1)"]}`,
+		},
+		{
+			name:               "text format",
+			query:              "?format=text",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     "1 (0/1) depth 0\n",
+		},
+		{
+			name:               "graph format",
+			query:              "?format=graph",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":{"nodes":[{"id":1,"capacity":1,"used":0,"depth":0}],"edges":[]}}`,
+		},
+		{
+			name:               "dot format",
+			query:              "?format=dot",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     "digraph network {\n  1 [label=\"1 (0/1)\"];\n}\n",
+		},
+		{
+			name:               "dot format of a version",
+			query:              "?format=dot&version=0",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"trace format \"dot\" does not support a version","error":true,"code":"bad_request","data":null}`,
+		},
+		{
+			name:               "unknown format",
+			query:              "?format=xml",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"unknown trace format \"xml\"","error":true,"code":"bad_request","data":null}`,
+		},
 	}
 
 	for _, testCase := range tableTest {
 		t.Run(testCase.name, func(t *testing.T) {
-			req, err := http.NewRequest(http.MethodGet, "/trace", nil)
+			req, err := http.NewRequest(http.MethodGet, "/trace"+testCase.query, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -143,25 +185,25 @@ func TestLeave(t *testing.T) {
 			name:               "happy case",
 			id:                 1,
 			expectedStatusCode: http.StatusAccepted,
-			expectedOutput:     `{"message":"successfully left","error":false,"data":1}`,
+			expectedOutput:     `{"message":"successfully left","error":false,"data":{"id":1,"reassignments":[],"disruption":{"id":1,"changed_parent":0,"rehomed":0,"depth_change":0}}}`,
 		},
 		{
 			name:               "negative value",
 			id:                 -1,
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"id must be a positive integer","error":true,"data":null}`,
+			expectedOutput:     `{"message":"id must be a positive integer","error":true,"code":"invalid_id","data":null}`,
 		},
 		{
 			name:               "not number",
 			id:                 0, // set to an alphabat
 			expectedStatusCode: http.StatusBadRequest,
-			expectedOutput:     `{"message":"strconv.Atoi: parsing \"a\": invalid syntax","error":true,"data":null}`,
+			expectedOutput:     `{"message":"strconv.Atoi: parsing \"a\": invalid syntax","error":true,"code":"bad_request","data":null}`,
 		},
 		{
 			name:               "not exists node",
 			id:                 2,
-			expectedStatusCode: http.StatusUnprocessableEntity,
-			expectedOutput:     `{"message":"cannot locate id 2 node","error":true,"data":null}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"cannot locate id 2 node","error":true,"code":"node_not_found","data":null}`,
 		},
 	}
 
@@ -202,3 +244,1070 @@ func TestLeave(t *testing.T) {
 		})
 	}
 }
+
+func TestMerge(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		joins              []string
+		expectedStatusCode int
+		expectedOutput     string
+		expectedTrace      string
+	}{
+		{
+			name:               "empty network",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"network merged","error":false,"data":0}`,
+			expectedTrace:      `{"message":"trace received","error":false,"data":null}`,
+		},
+		{
+			name:               "roots without capacity join a tree",
+			joins:              []string{`{"id":12, "capacity":0}`, `{"id":13, "capacity":0}`, `{"id":3, "capacity":3}`},
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"network merged","error":false,"data":2}`,
+			expectedTrace:      `{"message":"trace received","error":false,"data":["3(2/3)[ 12(0/0) 13(0/0) ]"]}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			hdl := newHandler(config.Default())
+
+			for _, join := range testCase.joins {
+				req, err := http.NewRequest(http.MethodPost, "/join", bytes.NewReader([]byte(join)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				hdl.Join(httptest.NewRecorder(), req)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/merge", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			hdl.Merge(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+
+			// check the network is merged
+			req, err = http.NewRequest(http.MethodGet, "/trace", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr = httptest.NewRecorder()
+
+			hdl.Trace(rr, req)
+
+			if rr.Body.String() != testCase.expectedTrace {
+				t.Errorf("expected %v, but got %v", testCase.expectedTrace, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		id                 string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "negative value",
+			id:                 "-1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"id must be a positive integer","error":true,"code":"invalid_id","data":null}`,
+		},
+		{
+			name:               "not number",
+			id:                 "a",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"strconv.Atoi: parsing \"a\": invalid syntax","error":true,"code":"bad_request","data":null}`,
+		},
+		{
+			name:               "not exists node",
+			id:                 "2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"cannot locate id 2 node","error":true,"code":"node_not_found","data":null}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/nodes/"+testCase.id+"/fail", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": testCase.id})
+
+			rr := httptest.NewRecorder()
+
+			h.Fail(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		id                 string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "negative value",
+			id:                 "-1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"id must be a positive integer","error":true,"code":"invalid_id","data":null}`,
+		},
+		{
+			name:               "not exists node",
+			id:                 "2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"cannot locate id 2 node","error":true,"code":"node_not_found","data":null}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/nodes/"+testCase.id+"/heartbeat", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": testCase.id})
+
+			rr := httptest.NewRecorder()
+
+			h.Heartbeat(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		reader             io.Reader
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "happy case",
+			reader:             bytes.NewReader([]byte(`{"level":"debug"}`)),
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"log level changed","error":false,"data":"debug"}`,
+		},
+		{
+			name:               "unknown level",
+			reader:             bytes.NewReader([]byte(`{"level":"warn"}`)),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"unknown log level \"warn\"","error":true,"code":"bad_request","data":null}`,
+		},
+		{
+			name:               "no body passed",
+			reader:             bytes.NewReader(nil),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"EOF","error":true,"code":"bad_request","data":null}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/log/level", testCase.reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			h.SetLogLevel(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+
+	// restore the default level for the other tests
+	h.logger.SetLevel(logging.Info)
+}
+
+func TestStats(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		joins              []string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "empty network",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"stats received","error":false,"data":{"peers":0,"trees":0,"max_depth":0,"free_capacity":0,"reorders":0,"rehomed":0}}`,
+		},
+		{
+			name:               "two peers",
+			joins:              []string{`{"id":1, "capacity":2}`, `{"id":2, "capacity":1}`},
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"stats received","error":false,"data":{"peers":2,"trees":1,"max_depth":1,"free_capacity":2,"reorders":0,"rehomed":0}}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			hdl := newHandler(config.Default())
+
+			for _, join := range testCase.joins {
+				req, err := http.NewRequest(http.MethodPost, "/join", bytes.NewReader([]byte(join)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				hdl.Join(httptest.NewRecorder(), req)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/stats", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			hdl.Stats(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	hdl := newHandler(config.Default())
+	router := initRouter(hdl)
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "nothing to undo",
+			method:             http.MethodPost,
+			path:               "/undo",
+			expectedStatusCode: http.StatusConflict,
+			expectedOutput:     `{"message":"nothing to undo","error":true,"code":"nothing_to_undo","data":null}`,
+		},
+		{
+			name:               "join",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":1, "capacity":1}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":1}`,
+		},
+		{
+			name:               "undo the join",
+			method:             http.MethodPost,
+			path:               "/undo",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"successfully undone","error":false,"data":0}`,
+		},
+		{
+			name:               "trace after undo",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":null}`,
+		},
+		{
+			name:               "trace of the undone version",
+			method:             http.MethodGet,
+			path:               "/trace?version=1",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["1(0/1)"]}`,
+		},
+		{
+			name:               "trace of the undone version as text",
+			method:             http.MethodGet,
+			path:               "/trace?version=1&format=text",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     "1 (0/1) depth 0\n",
+		},
+		{
+			name:               "redo the join",
+			method:             http.MethodPost,
+			path:               "/redo",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"successfully redone","error":false,"data":1}`,
+		},
+		{
+			name:               "nothing to redo",
+			method:             http.MethodPost,
+			path:               "/redo",
+			expectedStatusCode: http.StatusConflict,
+			expectedOutput:     `{"message":"nothing to redo","error":true,"code":"nothing_to_redo","data":null}`,
+		},
+		{
+			name:               "version not in the history",
+			method:             http.MethodGet,
+			path:               "/trace?version=2",
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"version 2 is not in the history, versions 0 to 1 are kept","error":true,"code":"version_not_found","data":null}`,
+		},
+		{
+			name:               "diff of the join",
+			method:             http.MethodGet,
+			path:               "/diff?from=0",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"diff received","error":false,"data":{"added":[1],"removed":[],"parents":[],"roots":[],"depths":[]}}`,
+		},
+		{
+			name:               "diff without from",
+			method:             http.MethodGet,
+			path:               "/diff?to=1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"from is required","error":true,"code":"bad_request","data":null}`,
+		},
+		{
+			name:               "diff to a version not in the history",
+			method:             http.MethodGet,
+			path:               "/diff?from=0&to=3",
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"version 3 is not in the history, versions 0 to 1 are kept","error":true,"code":"version_not_found","data":null}`,
+		},
+		{
+			name:               "invalid version",
+			method:             http.MethodGet,
+			path:               "/trace?version=last",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"version must be a none negative integer","error":true,"code":"bad_request","data":null}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	tableTest := []struct {
+		name               string
+		query              string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "dry run",
+			query:              "?dry_run=true",
+			body:               `{"operations":[{"type":"join","id":1,"capacity":2},{"type":"join","id":2}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"transaction evaluated","error":false,"data":{"trace":["1(1/2)[ 2(0/0) ]"],"stats":{"peers":2,"trees":1,"max_depth":1,"free_capacity":1,"reorders":0,"rehomed":0},"committed":false}}`,
+		},
+		{
+			name:               "commit",
+			body:               `{"operations":[{"type":"join","id":1,"capacity":2},{"type":"capacity","id":1,"capacity":1}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"transaction committed","error":false,"data":{"trace":["1(0/1)"],"stats":{"peers":1,"trees":1,"max_depth":0,"free_capacity":1,"reorders":0,"rehomed":0},"committed":true}}`,
+		},
+		{
+			name:               "failed operation",
+			body:               `{"operations":[{"type":"leave","id":1},{"type":"leave","id":1}]}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedOutput:     `{"message":"operation 1 (leave 1): cannot locate id 1 node","error":true,"code":"node_not_found","data":null}`,
+		},
+		{
+			name:               "unknown operation",
+			body:               `{"operations":[{"type":"fail","id":1}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"operation 0 (fail 1): unknown operation \"fail\"","error":true,"code":"invalid_operation","data":null}`,
+		},
+		{
+			name:               "no operations",
+			body:               `{"operations":[]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"operations are required","error":true,"code":"bad_request","data":null}`,
+		},
+		{
+			name:               "invalid dry run",
+			query:              "?dry_run=maybe",
+			body:               `{"operations":[{"type":"leave","id":1}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"dry_run must be true or false","error":true,"code":"bad_request","data":null}`,
+		},
+	}
+
+	hdl := newHandler(config.Default())
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/transactions"+testCase.query, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			hdl.Transaction(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	cfg := config.Default()
+	cfg.SourcesOnly = true
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "join without a source",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":0}`,
+			expectedStatusCode: http.StatusConflict,
+			expectedOutput:     `{"message":"no free capacity for node 2, only sources can start a tree","error":true,"code":"no_capacity","data":null}`,
+		},
+		{
+			name:               "add a source",
+			method:             http.MethodPost,
+			path:               "/sources",
+			body:               `{"id":1, "capacity":1}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"source added","error":false,"data":1}`,
+		},
+		{
+			name:               "invalid source",
+			method:             http.MethodPost,
+			path:               "/sources",
+			body:               `{"id":0, "capacity":1}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOutput:     `{"message":"id must be a positive integer","error":true,"code":"invalid_id","data":null}`,
+		},
+		{
+			name:               "join beneath the source",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":0}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":2}`,
+		},
+		{
+			name:               "trace is labelled with the source",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["source 1: 1(1/1)[ 2(0/0) ]"]}`,
+		},
+		{
+			name:               "leave of the source drops the peers without a place",
+			method:             http.MethodDelete,
+			path:               "/leave/1",
+			expectedStatusCode: http.StatusAccepted,
+			expectedOutput:     `{"message":"successfully left","error":false,"data":{"id":1,"reassignments":[],"dropped":[2],"disruption":{"id":1,"changed_parent":0,"rehomed":0,"depth_change":-1}}}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestQueue(t *testing.T) {
+	cfg := config.Default()
+	cfg.Queue = "fifo"
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "first node starts a tree",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":1, "capacity":0}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":1}`,
+		},
+		{
+			name:               "node without a place waits",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":3}`,
+			expectedStatusCode: http.StatusAccepted,
+			expectedOutput:     `{"message":"waiting for free capacity","error":false,"data":2}`,
+		},
+		{
+			name:               "waiting id is reserved",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":3}`,
+			expectedStatusCode: http.StatusConflict,
+			expectedOutput:     `{"message":"id 2 already reserved","error":true,"code":"duplicate_id","data":null}`,
+		},
+		{
+			name:               "trace without the waiting node",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["1(0/0)"]}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+
+	// the wait time changes, so only the waiting peers are compared
+	req, err := http.NewRequest(http.MethodGet, "/queue", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	var response struct {
+		Data []QueuedPeer `json:"data"`
+	}
+
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Data) != 1 || response.Data[0].Id != 2 || response.Data[0].Capacity != 3 || response.Data[0].Peers != 1 || response.Data[0].Wait < 0 {
+		t.Errorf("expected %v, but got %v", "node 2 waiting", rr.Body.String())
+	}
+}
+
+func TestMesh(t *testing.T) {
+	cfg := config.Default()
+	cfg.Topology = "mesh"
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	for _, body := range []string{`{"id":1, "capacity":2}`, `{"id":2, "capacity":1}`, `{"id":3, "capacity":1}`, `{"id":4, "capacity":0}`} {
+		req, err := http.NewRequest(http.MethodPost, "/join", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			// encoding/json escapes the < of the arrows
+			name:               "trace lists the parents",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["1(2/2)","2(1/1) \u003c- 1","3(1/1) \u003c- 1","4(0/0) \u003c- 2 3"]}`,
+		},
+		{
+			name:               "dot has an edge for each parent",
+			method:             http.MethodGet,
+			path:               "/trace?format=dot",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput: "digraph network {\n" +
+				"  1 [label=\"1 (2/2)\"];\n  2 [label=\"2 (1/1)\"];\n  3 [label=\"3 (1/1)\"];\n  4 [label=\"4 (0/0)\"];\n" +
+				"  1 -> 2;\n  1 -> 3;\n  2 -> 4;\n  3 -> 4;\n}\n",
+		},
+		{
+			name:               "undo is not supported",
+			method:             http.MethodPost,
+			path:               "/undo",
+			expectedStatusCode: http.StatusNotImplemented,
+			expectedOutput:     `{"message":"undo is not supported by the mesh topology","error":true,"code":"unsupported","data":null}`,
+		},
+		{
+			name:               "queue is not supported",
+			method:             http.MethodGet,
+			path:               "/queue",
+			expectedStatusCode: http.StatusNotImplemented,
+			expectedOutput:     `{"message":"the queue is not supported by the mesh topology","error":true,"code":"unsupported","data":null}`,
+		},
+		{
+			name:               "leave keeps the other parent",
+			method:             http.MethodDelete,
+			path:               "/leave/2",
+			expectedStatusCode: http.StatusAccepted,
+			expectedOutput:     `{"message":"successfully left","error":false,"data":{"id":2,"reassignments":[{"id":4,"old_parent":2,"new_parent":0,"root":false}],"disruption":{"id":2,"changed_parent":1,"rehomed":0,"depth_change":0}}}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStripes(t *testing.T) {
+	cfg := config.Default()
+	cfg.Topology = "stripes"
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	for _, body := range []string{`{"id":1, "capacity":2}`, `{"id":2, "capacity":2}`, `{"id":3, "capacity":1}`, `{"id":4, "capacity":1}`} {
+		req, err := http.NewRequest(http.MethodPost, "/join", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "trace is labelled with the stripe",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["stripe 0: 1(2/2)[ 2(0/0) 3(1/1)[ 4(0/0) ] ]","stripe 1: 4(1/1)[ 2(2/2)[ 1(0/0) 3(0/0) ] ]"]}`,
+		},
+		{
+			name:               "stats of each stripe",
+			method:             http.MethodGet,
+			path:               "/stats",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput: `{"message":"stats received","error":false,"data":{"peers":4,"trees":2,"max_depth":2,"free_capacity":0,"reorders":0,"rehomed":0,"max_forwarding":2,"mean_forwarding":1.5,"stripes":[` +
+				`{"stripe":0,"peers":4,"trees":1,"max_depth":2,"interior":2,"free_capacity":0},` +
+				`{"stripe":1,"peers":4,"trees":1,"max_depth":2,"interior":2,"free_capacity":0}]}}`,
+		},
+		{
+			name:               "crash is not supported",
+			method:             http.MethodPost,
+			path:               "/nodes/1/fail",
+			expectedStatusCode: http.StatusNotImplemented,
+			expectedOutput:     `{"message":"crashes are not supported by the stripes topology","error":true,"code":"unsupported","data":null}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLocality(t *testing.T) {
+	cfg := config.Default()
+	cfg.Locality = true
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "first node in eu",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":1, "capacity":4, "region":"eu", "x":0}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":1}`,
+		},
+		{
+			name:               "second node in us",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":2, "region":"us", "x":100}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":2}`,
+		},
+		{
+			name:               "third node joins the node in its region",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":3, "capacity":0, "region":"us", "x":100}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":3}`,
+		},
+		{
+			name:               "trace",
+			method:             http.MethodGet,
+			path:               "/trace",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"trace received","error":false,"data":["1(1/4)[ 2(1/2)[ 3(0/0) ] ]"]}`,
+		},
+		{
+			name:               "locality",
+			method:             http.MethodGet,
+			path:               "/locality",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"locality received","error":false,"data":[{"root":1,"edges":2,"cross_region":1,"distance":100}]}`,
+		},
+		{
+			name:               "a transaction joins the node in its region",
+			method:             http.MethodPost,
+			path:               "/transactions",
+			body:               `{"operations":[{"type":"join","id":4,"capacity":0,"region":"us","x":100}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"transaction committed","error":false,"data":{"trace":["1(1/4)[ 2(2/2)[ 3(0/0) 4(0/0) ] ]"],"stats":{"peers":4,"trees":1,"max_depth":2,"free_capacity":3,"reorders":0,"rehomed":0},"committed":true}}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestReputation(t *testing.T) {
+	cfg := config.Default()
+	cfg.StableAfter = time.Hour
+
+	hdl := newHandler(cfg)
+	router := initRouter(hdl)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/join", body: `{"id":1, "capacity":2}`},
+		{method: http.MethodPost, path: "/join", body: `{"id":2, "capacity":1}`},
+		{method: http.MethodDelete, path: "/leave/2"},
+		{method: http.MethodPost, path: "/join", body: `{"id":2, "capacity":1}`},
+	}
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.method, request.path, bytes.NewReader([]byte(request.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// the sessions are measured by the clock, so only the session counts are compared
+	req, err := http.NewRequest(http.MethodGet, "/reputation", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	var response struct {
+		Data []Reputation `json:"data"`
+	}
+
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Reputation{
+		{Id: 1, Joins: 1, Online: true},
+		{Id: 2, Joins: 2, Online: true},
+	}
+
+	if len(response.Data) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, rr.Body.String())
+	}
+
+	for i := range expected {
+		response.Data[i].AverageSession = 0
+
+		if response.Data[i] != expected[i] {
+			t.Errorf("expected %+v, but got %+v", expected[i], response.Data[i])
+		}
+	}
+}
+
+func TestDisruption(t *testing.T) {
+	hdl := newHandler(config.Default())
+	router := initRouter(hdl)
+
+	tableTest := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedOutput     string
+	}{
+		{
+			name:               "no leaves",
+			method:             http.MethodGet,
+			path:               "/stats/disruption",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"disruption stats received","error":false,"data":{"leaves":0,"changed_parent":0,"rehomed":0,"depth_change":0,"records":[]}}`,
+		},
+		{
+			name:               "first node",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":1, "capacity":1}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":1}`,
+		},
+		{
+			name:               "second node",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":2, "capacity":1}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":2}`,
+		},
+		{
+			name:               "third node",
+			method:             http.MethodPost,
+			path:               "/join",
+			body:               `{"id":3, "capacity":0}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedOutput:     `{"message":"successfully joined","error":false,"data":3}`,
+		},
+		{
+			name:               "leave of the middle node",
+			method:             http.MethodDelete,
+			path:               "/leave/2",
+			expectedStatusCode: http.StatusAccepted,
+			expectedOutput:     `{"message":"successfully left","error":false,"data":{"id":2,"reassignments":[{"id":3,"old_parent":2,"new_parent":1,"root":false}],"disruption":{"id":2,"changed_parent":1,"rehomed":0,"depth_change":-1}}}`,
+		},
+		{
+			name:               "leave of a leaf",
+			method:             http.MethodDelete,
+			path:               "/leave/3",
+			expectedStatusCode: http.StatusAccepted,
+			expectedOutput:     `{"message":"successfully left","error":false,"data":{"id":3,"reassignments":[],"disruption":{"id":3,"changed_parent":0,"rehomed":0,"depth_change":-1}}}`,
+		},
+		{
+			name:               "running totals",
+			method:             http.MethodGet,
+			path:               "/stats/disruption",
+			expectedStatusCode: http.StatusOK,
+			expectedOutput:     `{"message":"disruption stats received","error":false,"data":{"leaves":2,"changed_parent":1,"rehomed":0,"depth_change":-2,"records":[{"id":2,"changed_parent":1,"rehomed":0,"depth_change":-1},{"id":3,"changed_parent":0,"rehomed":0,"depth_change":-1}]}}`,
+		},
+	}
+
+	for _, testCase := range tableTest {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, bytes.NewReader([]byte(testCase.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			// check the status code is what we expect.
+			if rr.Code != testCase.expectedStatusCode {
+				t.Errorf("expected %v, but got %v", testCase.expectedStatusCode, rr.Code)
+			}
+
+			// check the response body is what we expect.
+			if rr.Body.String() != testCase.expectedOutput {
+				t.Errorf("expected %v, but got %v", testCase.expectedOutput, rr.Body.String())
+			}
+		})
+	}
+}
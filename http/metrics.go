@@ -0,0 +1,186 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// upper bounds of the request latency histogram buckets, in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey: labels of the request counter
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+// latencyKey: labels of the request latency histogram
+type latencyKey struct {
+	route  string
+	method string
+}
+
+// histogram: cumulative counts of the observed values per bucket
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// metrics: collects the request metrics of the routes in Prometheus text format
+type metrics struct {
+	requests  map[requestKey]uint64
+	latencies map[latencyKey]*histogram
+
+	// using mutex to prevent from the concurrent accesses to the metrics
+	lock sync.Mutex
+}
+
+// newMetrics: creates empty metrics
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[latencyKey]*histogram),
+	}
+}
+
+// observe: records a request for the given route
+func (m *metrics) observe(route string, method string, status int, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.requests[requestKey{route: route, method: method, status: status}]++
+
+	key := latencyKey{route: route, method: method}
+
+	h, ok := m.latencies[key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.latencies[key] = h
+	}
+
+	seconds := duration.Seconds()
+
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+
+	h.count++
+	h.sum += seconds
+}
+
+// middleware: records the count and the latency of the requests per route template
+func (m *metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		m.observe(routeTemplate(r), r.Method, recorder.status, time.Since(start))
+	})
+}
+
+// write: writes the request metrics in Prometheus text format
+func (m *metrics) write(w io.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	fmt.Fprintln(w, "# HELP http_requests_total Number of HTTP requests per route, method and status code.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+
+	requests := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requests = append(requests, key)
+	}
+
+	// sort the series, so the output is stable between scrapes
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+
+		if a.route != b.route {
+			return a.route < b.route
+		}
+
+		if a.method != b.method {
+			return a.method < b.method
+		}
+
+		return a.status < b.status
+	})
+
+	for _, key := range requests {
+		fmt.Fprintf(w, "http_requests_total{route=%q,method=%q,status=\"%d\"} %d\n", key.route, key.method, key.status, m.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Latency of HTTP requests per route and method.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+
+	latencies := make([]latencyKey, 0, len(m.latencies))
+	for key := range m.latencies {
+		latencies = append(latencies, key)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		a, b := latencies[i], latencies[j]
+
+		if a.route != b.route {
+			return a.route < b.route
+		}
+
+		return a.method < b.method
+	})
+
+	for _, key := range latencies {
+		h := m.latencies[key]
+		labels := fmt.Sprintf("route=%q,method=%q", key.route, key.method)
+
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, formatFloat(bound), h.buckets[i])
+		}
+
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %s\n", labels, formatFloat(h.sum))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+}
+
+// writeMetric: writes a single metric without labels in Prometheus text format
+func writeMetric(w io.Writer, name string, kind string, help string, value int) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+// formatFloat: formats the given value the shortest way, like Prometheus does
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// statusRecorder: keeps track of the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader: records the status code and writes it
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Flush: sends the buffered data to the client, if the underlying writer supports it
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
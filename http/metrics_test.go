@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"p2p-network-simulator/config"
+)
+
+func TestMetrics(t *testing.T) {
+	router := initRouter(newHandler(config.Default()))
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/join", body: `{"id":1, "capacity":1}`},
+		{method: http.MethodPost, path: "/join", body: `{"id":2, "capacity":0}`},
+		{method: http.MethodPost, path: "/join", body: `{"id":2, "capacity":0}`},
+		{method: http.MethodDelete, path: "/leave/9", body: ""},
+	}
+
+	for _, request := range requests {
+		req, err := http.NewRequest(request.method, request.path, bytes.NewReader([]byte(request.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v, but got %v", http.StatusOK, rr.Code)
+	}
+
+	expected := []string{
+		`http_requests_total{route="/join",method="POST",status="201"} 2`,
+		`http_requests_total{route="/join",method="POST",status="409"} 1`,
+		`http_requests_total{route="/leave/{id}",method="DELETE",status="404"} 1`,
+		`http_request_duration_seconds_bucket{route="/join",method="POST",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{route="/leave/{id}",method="DELETE"} 1`,
+		"# TYPE p2p_peers gauge\np2p_peers 2",
+		"p2p_trees 1",
+		"p2p_max_depth 1",
+		"p2p_free_capacity 0",
+		"p2p_reorders_total 0",
+		"p2p_rehomed_subtrees_total 0",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("expected %s in\n%s", line, rr.Body.String())
+		}
+	}
+}
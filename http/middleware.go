@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"p2p-network-simulator/logging"
+
+	"github.com/gorilla/mux"
+)
+
+// header which carries the id of the request, assigned if the client does not send one
+const requestIdHeader = "X-Request-ID"
+
+type contextKey int
+
+// loggerKey: key of the request scoped logger in the request context
+const loggerKey contextKey = iota
+
+// requestLogger: assigns or propagates the request id, attaches a request scoped logger to the request
+// and logs the status code and the duration of every request
+func (hdl handler) requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIdHeader)
+		if id == "" {
+			id = newRequestId()
+		}
+
+		w.Header().Set(requestIdHeader, id)
+
+		logger := hdl.logger.With(
+			logging.F("request_id", id),
+			logging.F("method", r.Method),
+			logging.F("route", routeTemplate(r)),
+		)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), loggerKey, logger)))
+
+		logger.Info("request completed",
+			logging.F("status", recorder.status),
+			logging.F("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
+
+// log: returns the request scoped logger, or the logger of the handler if the request has none
+func (hdl handler) log(r *http.Request) *logging.Logger {
+	logger, ok := r.Context().Value(loggerKey).(*logging.Logger)
+	if !ok {
+		return hdl.logger
+	}
+
+	return logger
+}
+
+// newRequestId: returns a random request id
+func newRequestId() string {
+	id := make([]byte, 8)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// routeTemplate: returns the route template matched by the request (/leave/{id}), or the path if none matched
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return r.URL.Path
+}
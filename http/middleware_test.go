@@ -0,0 +1,70 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/logging"
+)
+
+func TestRequestLogger(t *testing.T) {
+	testTable := []struct {
+		name       string
+		requestId  string
+		expectedId func(id string) bool
+	}{
+		{
+			name:       "propagates the request id",
+			requestId:  "abc-123",
+			expectedId: func(id string) bool { return id == "abc-123" },
+		},
+		{
+			name:       "assigns a request id",
+			requestId:  "",
+			expectedId: func(id string) bool { return len(id) == 16 },
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+
+			hdl := newHandler(config.Default())
+			hdl.logger = logging.New(&buffer, logging.Info, "logfmt")
+
+			router := initRouter(hdl)
+
+			req, err := http.NewRequest(http.MethodDelete, "/leave/5", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testCase.requestId != "" {
+				req.Header.Set(requestIdHeader, testCase.requestId)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(requestIdHeader)
+			if !testCase.expectedId(id) {
+				t.Errorf("unexpected request id %q", id)
+			}
+
+			expected := []string{
+				"level=error msg=\"cannot locate id 5 node\" request_id=" + id + " method=DELETE route=/leave/{id} node=5 status=404",
+				"level=info msg=\"request completed\" request_id=" + id + " method=DELETE route=/leave/{id} status=404 duration_ms=",
+			}
+
+			for _, line := range expected {
+				if !strings.Contains(buffer.String(), line) {
+					t.Errorf("expected %s in\n%s", line, buffer.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openapi: OpenAPI document of the service
+//
+//go:embed openapi.json
+var openapi []byte
+
+// OpenAPI: controller for get the OpenAPI document of the service
+func (hdl handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi)
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"p2p-network-simulator/config"
+
+	"github.com/gorilla/mux"
+)
+
+// document: the parts of the OpenAPI document used by the tests
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Parameters map[string]parameter `json:"parameters"`
+		Schemas    map[string]schema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref    string `json:"$ref"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+	Schema schema `json:"schema"`
+}
+
+type schema struct {
+	Ref        string            `json:"$ref"`
+	Type       string            `json:"type"`
+	Minimum    *int              `json:"minimum"`
+	Enum       []string          `json:"enum"`
+	Required   []string          `json:"required"`
+	Properties map[string]schema `json:"properties"`
+}
+
+// loadDocument: decodes the embedded OpenAPI document
+func loadDocument(t *testing.T) document {
+	t.Helper()
+
+	var doc document
+
+	err := json.Unmarshal(openapi, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// name: returns the last part of the reference (#/components/schemas/Node -> Node)
+func name(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := loadDocument(t)
+	router := initRouter(newHandler(config.Default()))
+
+	registered := make(map[string]bool)
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			method = strings.ToLower(method)
+			registered[method+" "+template] = true
+
+			if _, ok := doc.Paths[template][method]; !ok {
+				t.Errorf("%s %s is not in the OpenAPI document", method, template)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every operation in the document is served
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is not registered in the router", method, path)
+			}
+		}
+	}
+}
+
+// TestOpenAPIValidation: sends the requests violating the constraints of the document,
+// and expects the service to reject them with bad request
+func TestOpenAPIValidation(t *testing.T) {
+	doc := loadDocument(t)
+	router := initRouter(newHandler(config.Default()))
+
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			// parameters below the minimum and query parameters out of the enum
+			for _, p := range op.Parameters {
+				if p.Ref != "" {
+					p = doc.Components.Parameters[name(p.Ref)]
+				}
+
+				switch {
+				case p.In == "path" && p.Schema.Minimum != nil:
+					target := strings.ReplaceAll(path, "{"+p.Name+"}", strconv.Itoa(*p.Schema.Minimum-1))
+
+					assertBadRequest(t, router, strings.ToUpper(method), target, nil)
+
+				case p.In == "query" && len(p.Schema.Enum) > 0:
+					target := path + "?" + p.Name + "=not-" + p.Schema.Enum[0]
+
+					assertBadRequest(t, router, strings.ToUpper(method), target, nil)
+
+				case p.In == "query" && p.Schema.Minimum != nil:
+					target := path + "?" + p.Name + "=" + strconv.Itoa(*p.Schema.Minimum-1)
+
+					assertBadRequest(t, router, strings.ToUpper(method), target, nil)
+				}
+			}
+
+			if op.RequestBody == nil {
+				continue
+			}
+
+			body := op.RequestBody.Content["application/json"].Schema
+			if body.Ref != "" {
+				body = doc.Components.Schemas[name(body.Ref)]
+			}
+
+			// a valid body, then break one property at a time
+			valid := make(map[string]interface{})
+
+			for property, s := range body.Properties {
+				switch {
+				case len(s.Enum) > 0:
+					valid[property] = s.Enum[0]
+				case s.Minimum != nil:
+					valid[property] = *s.Minimum + 100
+				}
+			}
+
+			for property, s := range body.Properties {
+				invalid := make(map[string]interface{})
+				for k, v := range valid {
+					invalid[k] = v
+				}
+
+				switch {
+				case len(s.Enum) > 0:
+					invalid[property] = "not-" + s.Enum[0]
+				case s.Minimum != nil:
+					invalid[property] = *s.Minimum - 1
+				default:
+					continue
+				}
+
+				assertBadRequest(t, router, strings.ToUpper(method), path, invalid)
+			}
+
+			// required properties missing
+			for _, property := range body.Required {
+				invalid := make(map[string]interface{})
+				for k, v := range valid {
+					if k != property {
+						invalid[k] = v
+					}
+				}
+
+				assertBadRequest(t, router, strings.ToUpper(method), path, invalid)
+			}
+		}
+	}
+}
+
+// assertBadRequest: sends the request and expects bad request
+func assertBadRequest(t *testing.T, router http.Handler, method string, path string, body map[string]interface{}) {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	if body == nil {
+		payload = nil
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("%s %s %s: expected %d, but got %d", method, path, payload, http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	h.OpenAPI(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v, but got %v", http.StatusOK, rr.Code)
+	}
+
+	if !json.Valid(rr.Body.Bytes()) {
+		t.Errorf("expected a valid JSON document")
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"p2p-network-simulator/logging"
+)
+
+// reaper: periodically evicts the nodes without a heartbeat within the TTL of the network
+type reaper struct {
+	handler  handler
+	interval time.Duration
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newReaper: creates a reaper which checks the heartbeats on every interval
+func newReaper(hdl handler, interval time.Duration) *reaper {
+	return &reaper{
+		handler:  hdl,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start: runs the reaper in a background goroutine
+func (rp *reaper) Start() {
+	rp.wg.Add(1)
+
+	go rp.run()
+}
+
+// Stop: stops the reaper and waits for the background goroutine to return
+func (rp *reaper) Stop() {
+	close(rp.done)
+
+	rp.wg.Wait()
+}
+
+// run: evicts the silent nodes until the reaper stops
+func (rp *reaper) run() {
+	defer rp.wg.Done()
+
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rp.done:
+			return
+
+		case <-ticker.C:
+			rp.reap()
+		}
+	}
+}
+
+// reap: evicts the silent nodes, then logs and emits each eviction
+func (rp *reaper) reap() {
+	reports := rp.handler.usecase.Evict()
+
+	for _, report := range reports {
+		rp.handler.logger.Info("node evicted", logging.F("node", report.Id), logging.F("reassigned", len(report.Reassignments)))
+
+		rp.handler.publish("evict", report.Id, newLeaveReport(report))
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/domain/usecases"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage"
+)
+
+func TestReaper(t *testing.T) {
+	network := storage.NewP2PNetworkWithOptions(storage.Options{TTL: time.Millisecond * 10})
+
+	hdl := handler{
+		usecase: usecases.NewSimulator(network),
+		events:  newBroker(),
+		logger:  logging.Discard(),
+	}
+
+	events := hdl.events.Subscribe()
+
+	network.Join(entities.Node{Id: 1, Capacity: 1})
+
+	rp := newReaper(hdl, time.Millisecond*5)
+	rp.Start()
+
+	select {
+	case event := <-events:
+		if event.Type != "evict" || event.Id != 1 {
+			t.Errorf("expected evict 1, but got %s %d", event.Type, event.Id)
+		}
+
+	case <-time.After(time.Second):
+		t.Errorf("expected an eviction")
+	}
+
+	rp.Stop()
+
+	if len(network.Trace()) != 0 {
+		t.Errorf("expected empty network, but got %v", network.Trace())
+	}
+}
+
+func TestReaperWithoutTTL(t *testing.T) {
+	cfg := config.Default()
+
+	// nothing expires without a ttl, so no reaper runs
+	if server := NewHTTPServer(cfg); server.reaper != nil {
+		t.Errorf("expected no reaper, but got %v", server.reaper)
+	}
+
+	cfg.TTL = time.Second * 10
+
+	server := NewHTTPServer(cfg)
+	if server.reaper == nil || server.reaper.interval != reaperInterval {
+		t.Errorf("expected a reaper every %v, but got %v", reaperInterval, server.reaper)
+	}
+}
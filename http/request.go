@@ -3,15 +3,89 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"p2p-network-simulator/domain/entities"
+
+	"github.com/gorilla/mux"
 )
 
 type Node struct {
-	Id       int `json:"id"`
-	Capacity int `json:"capacity"`
+	Id       int     `json:"id"`
+	Capacity int     `json:"capacity"`
+	Region   string  `json:"region,omitempty"`
+	X        float64 `json:"x,omitempty"`
+	Y        float64 `json:"y,omitempty"`
+}
+
+func newNode(node entities.Node) Node {
+	return Node{
+		Id:       node.Id,
+		Capacity: node.Capacity,
+		Region:   node.Region,
+		X:        node.X,
+		Y:        node.Y,
+	}
+}
+
+// Operation: a single change of a transaction
+type Operation struct {
+	Type     string  `json:"type"`
+	Id       int     `json:"id"`
+	Capacity int     `json:"capacity"`
+	Region   string  `json:"region,omitempty"`
+	X        float64 `json:"x,omitempty"`
+	Y        float64 `json:"y,omitempty"`
+}
+
+// Transaction: operations applied together
+type Transaction struct {
+	Operations []Operation `json:"operations"`
+}
+
+// decodeTransaction: retrives the operations of a transaction from the request body
+func decodeTransaction(r *http.Request) ([]entities.Operation, error) {
+	var transaction Transaction
+
+	err := json.NewDecoder(r.Body).Decode(&transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transaction.Operations) == 0 {
+		return nil, errors.New("operations are required")
+	}
+
+	operations := make([]entities.Operation, 0, len(transaction.Operations))
+
+	for _, o := range transaction.Operations {
+		operations = append(operations, entities.Operation{
+			Type:     o.Type,
+			Id:       o.Id,
+			Capacity: o.Capacity,
+			Location: entities.Location{Region: o.Region, X: o.X, Y: o.Y},
+		})
+	}
+
+	return operations, nil
+}
+
+// decodeDryRun: retrives the dry run flag from the query. False, if it is not given
+func decodeDryRun(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("dry_run must be true or false")
+	}
+
+	return dryRun, nil
 }
 
 func decodeRequest(r *http.Request) (entities.Node, error) {
@@ -33,12 +107,44 @@ func decodeRequest(r *http.Request) (entities.Node, error) {
 
 	// validate id and capacity
 	if node.Id < 1 {
-		return node, errors.New("id must be a positive integer")
+		return node, entities.ErrInvalidID
 	}
 
 	if node.Capacity < 0 {
-		return node, errors.New("capacity must be none negative")
+		return node, entities.ErrInvalidCapacity
 	}
 
 	return node, nil
 }
+
+// decodeId: retrives the node id from the request path
+func decodeId(r *http.Request) (int, error) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return id, err
+	}
+
+	if id < 1 {
+		return id, entities.ErrInvalidID
+	}
+
+	return id, nil
+}
+
+// decodeVersion: retrives the version of the topology from the query parameter with the given name.
+// Returns -1, if there is no version
+func decodeVersion(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return -1, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return -1, fmt.Errorf("%s must be a none negative integer", name)
+	}
+
+	return version, nil
+}
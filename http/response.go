@@ -3,18 +3,320 @@ package http
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
 )
 
 type Data struct {
 	Message string      `json:"message"`
 	Error   bool        `json:"error"`
+	Code    string      `json:"code,omitempty"` // machine readable error code
 	Data    interface{} `json:"data"`
 }
 
+// Reassignment: the new parent of a peer after another peer left.
+// Parent is zero, if the peer is a root
+type Reassignment struct {
+	Id        int  `json:"id"`
+	OldParent int  `json:"old_parent"`
+	NewParent int  `json:"new_parent"`
+	Root      bool `json:"root"`
+	Stripe    *int `json:"stripe,omitempty"` // only in the stripes topology
+}
+
+// LeaveReport: the peers which have to reconnect to a new parent after a leave
+type LeaveReport struct {
+	Id            int            `json:"id"`
+	Reassignments []Reassignment `json:"reassignments"`
+	Dropped       []int          `json:"dropped,omitempty"` // peers which left with the peer, since no source had a place for them
+	Disruption    Disruption     `json:"disruption"`
+}
+
+// Disruption: the cost of a leave for the peers which stay in the network
+type Disruption struct {
+	Id            int `json:"id"`
+	ChangedParent int `json:"changed_parent"`
+	Rehomed       int `json:"rehomed"`
+	DepthChange   int `json:"depth_change"`
+}
+
+func newDisruption(disruption entities.Disruption) Disruption {
+	return Disruption{
+		Id:            disruption.Id,
+		ChangedParent: disruption.ChangedParent,
+		Rehomed:       disruption.Rehomed,
+		DepthChange:   disruption.DepthChange,
+	}
+}
+
+// DisruptionStats: running totals of the disruptions of the leaves, with the records of the latest leaves
+type DisruptionStats struct {
+	Leaves        int          `json:"leaves"`
+	ChangedParent int          `json:"changed_parent"`
+	Rehomed       int          `json:"rehomed"`
+	DepthChange   int          `json:"depth_change"`
+	Records       []Disruption `json:"records"`
+}
+
+func newDisruptionStats(stats entities.DisruptionStats) DisruptionStats {
+	records := make([]Disruption, 0, len(stats.Records))
+
+	for _, record := range stats.Records {
+		records = append(records, newDisruption(record))
+	}
+
+	return DisruptionStats{
+		Leaves:        stats.Leaves,
+		ChangedParent: stats.ChangedParent,
+		Rehomed:       stats.Rehomed,
+		DepthChange:   stats.DepthChange,
+		Records:       records,
+	}
+}
+
+func newLeaveReport(report entities.LeaveReport) LeaveReport {
+	reassignments := make([]Reassignment, 0, len(report.Reassignments))
+
+	for _, r := range report.Reassignments {
+		reassignments = append(reassignments, Reassignment{
+			Id:        r.Id,
+			OldParent: r.OldParent,
+			NewParent: r.NewParent,
+			Root:      r.Root,
+			Stripe:    r.Stripe,
+		})
+	}
+
+	return LeaveReport{
+		Id:            report.Id,
+		Reassignments: reassignments,
+		Dropped:       report.Dropped,
+		Disruption:    newDisruption(report.Disruption),
+	}
+}
+
+// FailureReport: the peers disconnected by a crashed peer and for how long
+type FailureReport struct {
+	Id           int       `json:"id"`
+	Disconnected int       `json:"disconnected"`
+	FailedAt     time.Time `json:"failed_at"`
+	RepairAt     time.Time `json:"repair_at"`
+	Window       int64     `json:"window_ms"`
+}
+
+func newFailureReport(report entities.FailureReport) FailureReport {
+	return FailureReport{
+		Id:           report.Id,
+		Disconnected: report.Disconnected,
+		FailedAt:     report.FailedAt,
+		RepairAt:     report.RepairAt,
+		Window:       report.RepairAt.Sub(report.FailedAt).Milliseconds(),
+	}
+}
+
+// QueuedPeer: a peer waiting for free capacity and for how long
+type QueuedPeer struct {
+	Id       int       `json:"id"`
+	Capacity int       `json:"capacity"`
+	Peers    int       `json:"peers"`
+	Since    time.Time `json:"since"`
+	Wait     int64     `json:"wait_ms"`
+}
+
+func newQueue(queue []entities.QueuedPeer, now time.Time) []QueuedPeer {
+	peers := make([]QueuedPeer, 0, len(queue))
+
+	for _, q := range queue {
+		peers = append(peers, QueuedPeer{
+			Id:       q.Id,
+			Capacity: q.Capacity,
+			Peers:    q.Peers,
+			Since:    q.Since,
+			Wait:     now.Sub(q.Since).Milliseconds(),
+		})
+	}
+
+	return peers
+}
+
+// Stats: shape of the network and the work done to keep it balanced.
+// The forwarding load and the stripes are given when the content is split into stripes
+type Stats struct {
+	Peers          int           `json:"peers"`
+	Trees          int           `json:"trees"`
+	MaxDepth       int           `json:"max_depth"`
+	FreeCapacity   int           `json:"free_capacity"`
+	Reorders       int           `json:"reorders"`
+	Rehomed        int           `json:"rehomed"`
+	MaxForwarding  int           `json:"max_forwarding,omitempty"`
+	MeanForwarding float64       `json:"mean_forwarding,omitempty"`
+	Stripes        []StripeStats `json:"stripes,omitempty"`
+}
+
+func newStats(stats entities.Stats) Stats {
+	return Stats{
+		Peers:          stats.Peers,
+		Trees:          stats.Trees,
+		MaxDepth:       stats.MaxDepth,
+		FreeCapacity:   stats.FreeCapacity,
+		Reorders:       stats.Reorders,
+		Rehomed:        stats.Rehomed,
+		MaxForwarding:  stats.MaxForwarding,
+		MeanForwarding: stats.MeanForwarding,
+	}
+}
+
+// StripeStats: shape of the trees of a stripe
+type StripeStats struct {
+	Stripe       int `json:"stripe"`
+	Peers        int `json:"peers"`
+	Trees        int `json:"trees"`
+	MaxDepth     int `json:"max_depth"`
+	Interior     int `json:"interior"`
+	FreeCapacity int `json:"free_capacity"`
+}
+
+func newStripes(stripes []entities.StripeStats) []StripeStats {
+	result := make([]StripeStats, 0, len(stripes))
+
+	for _, s := range stripes {
+		result = append(result, StripeStats{
+			Stripe:       s.Stripe,
+			Peers:        s.Peers,
+			Trees:        s.Trees,
+			MaxDepth:     s.MaxDepth,
+			Interior:     s.Interior,
+			FreeCapacity: s.FreeCapacity,
+		})
+	}
+
+	return result
+}
+
+// TreeLocality: the edges of a tree between peers which are not in the same region
+type TreeLocality struct {
+	Root        int     `json:"root"`
+	Edges       int     `json:"edges"`
+	CrossRegion int     `json:"cross_region"`
+	Distance    float64 `json:"distance"`
+}
+
+func newLocality(report []entities.TreeLocality) []TreeLocality {
+	result := make([]TreeLocality, 0, len(report))
+
+	for _, l := range report {
+		result = append(result, TreeLocality{
+			Root:        l.Root,
+			Edges:       l.Edges,
+			CrossRegion: l.CrossRegion,
+			Distance:    l.Distance,
+		})
+	}
+
+	return result
+}
+
+// Reputation: the session history of a peer id
+type Reputation struct {
+	Id             int   `json:"id"`
+	Joins          int   `json:"joins"`
+	Failures       int   `json:"failures"`
+	AverageSession int64 `json:"average_session_ms"`
+	Online         bool  `json:"online"`
+	Stable         bool  `json:"stable"`
+}
+
+func newReputation(reputation []entities.Reputation) []Reputation {
+	result := make([]Reputation, 0, len(reputation))
+
+	for _, r := range reputation {
+		result = append(result, Reputation{
+			Id:             r.Id,
+			Joins:          r.Joins,
+			Failures:       r.Failures,
+			AverageSession: r.AverageSession.Milliseconds(),
+			Online:         r.Online,
+			Stable:         r.Stable,
+		})
+	}
+
+	return result
+}
+
+// ParentChange: a peer attached to a different parent. Parent is zero, if the peer is a root
+type ParentChange struct {
+	Id        int `json:"id"`
+	OldParent int `json:"old_parent"`
+	NewParent int `json:"new_parent"`
+}
+
+// RootChange: a peer which became a root or stopped being one
+type RootChange struct {
+	Id   int  `json:"id"`
+	Root bool `json:"root"`
+}
+
+// DepthChange: a peer which moved to a different level of its tree
+type DepthChange struct {
+	Id       int `json:"id"`
+	OldDepth int `json:"old_depth"`
+	NewDepth int `json:"new_depth"`
+}
+
+// Diff: the differences between two topologies
+type Diff struct {
+	Added   []int          `json:"added"`
+	Removed []int          `json:"removed"`
+	Parents []ParentChange `json:"parents"`
+	Roots   []RootChange   `json:"roots"`
+	Depths  []DepthChange  `json:"depths"`
+}
+
+func newDiff(diff entities.Diff) Diff {
+	result := Diff{
+		Added:   diff.Added,
+		Removed: diff.Removed,
+		Parents: make([]ParentChange, 0, len(diff.Parents)),
+		Roots:   make([]RootChange, 0, len(diff.Roots)),
+		Depths:  make([]DepthChange, 0, len(diff.Depths)),
+	}
+
+	for _, c := range diff.Parents {
+		result.Parents = append(result.Parents, ParentChange{Id: c.Id, OldParent: c.OldParent, NewParent: c.NewParent})
+	}
+
+	for _, c := range diff.Roots {
+		result.Roots = append(result.Roots, RootChange{Id: c.Id, Root: c.Root})
+	}
+
+	for _, c := range diff.Depths {
+		result.Depths = append(result.Depths, DepthChange{Id: c.Id, OldDepth: c.OldDepth, NewDepth: c.NewDepth})
+	}
+
+	return result
+}
+
+// TransactionResult: the network after every operation of a transaction is applied
+type TransactionResult struct {
+	Trace     []string `json:"trace"`
+	Stats     Stats    `json:"stats"`
+	Committed bool     `json:"committed"`
+}
+
+func newTransactionResult(result entities.TransactionResult) TransactionResult {
+	return TransactionResult{
+		Trace:     result.Trace,
+		Stats:     newStats(result.Stats),
+		Committed: result.Committed,
+	}
+}
+
 func handleError(w http.ResponseWriter, err error, status int) {
 	response := Data{
 		Message: err.Error(),
 		Error:   true,
+		Code:    errorCode(err, status),
 	}
 
 	payload, _ := json.Marshal(response)
@@ -6,18 +6,41 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func initRouter() *mux.Router {
+func initRouter(handler handler) *mux.Router {
 	r := mux.NewRouter()
 
-	handler := newHandler()
+	// assign the request ids and log the requests
+	r.Use(handler.requestLogger)
+
+	// count the requests and their latency per route
+	r.Use(handler.metrics.middleware)
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 	}).Methods(http.MethodGet)
 
 	// define endpoints
 	r.HandleFunc("/join", handler.Join).Methods(http.MethodPost)
+	r.HandleFunc("/sources", handler.AddSource).Methods(http.MethodPost)
 	r.HandleFunc("/leave/{id}", handler.Leave).Methods(http.MethodDelete)
 	r.HandleFunc("/trace", handler.Trace).Methods(http.MethodGet)
+	r.HandleFunc("/merge", handler.Merge).Methods(http.MethodPost)
+	r.HandleFunc("/transactions", handler.Transaction).Methods(http.MethodPost)
+	r.HandleFunc("/undo", handler.Undo).Methods(http.MethodPost)
+	r.HandleFunc("/redo", handler.Redo).Methods(http.MethodPost)
+	r.HandleFunc("/diff", handler.Diff).Methods(http.MethodGet)
+	r.HandleFunc("/nodes/{id}/fail", handler.Fail).Methods(http.MethodPost)
+	r.HandleFunc("/nodes/{id}/heartbeat", handler.Heartbeat).Methods(http.MethodPost)
+	r.HandleFunc("/stats", handler.Stats).Methods(http.MethodGet)
+	r.HandleFunc("/stats/disruption", handler.Disruption).Methods(http.MethodGet)
+	r.HandleFunc("/queue", handler.Queue).Methods(http.MethodGet)
+	r.HandleFunc("/locality", handler.Locality).Methods(http.MethodGet)
+	r.HandleFunc("/reputation", handler.Reputation).Methods(http.MethodGet)
+	r.HandleFunc("/metrics", handler.Metrics).Methods(http.MethodGet)
+	r.HandleFunc("/log/level", handler.LogLevel).Methods(http.MethodGet)
+	r.HandleFunc("/log/level", handler.SetLogLevel).Methods(http.MethodPut)
+	r.HandleFunc("/openapi.json", handler.OpenAPI).Methods(http.MethodGet)
+	r.HandleFunc("/events", handler.Events).Methods(http.MethodGet)
+	r.HandleFunc("/ui", handler.UI).Methods(http.MethodGet)
 
 	return r
 }
@@ -2,30 +2,64 @@ package http
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/logging"
 )
 
+// max interval between two checks of the heartbeats
+const reaperInterval = time.Second
+
 type HTTPServer struct {
-	server *http.Server
+	server  *http.Server
+	config  config.Config
+	handler handler
+	reaper  *reaper
 }
 
-func NewHTTPServer() *HTTPServer {
-	return &HTTPServer{}
+func NewHTTPServer(cfg config.Config) *HTTPServer {
+	handler := newHandler(cfg)
+
+	server := &HTTPServer{
+		config:  cfg,
+		handler: handler,
+	}
+
+	// without a ttl no node expires, so there is nothing to reap
+	if cfg.TTL <= 0 {
+		return server
+	}
+
+	// check the heartbeats at least twice within the ttl
+	interval := reaperInterval
+	if cfg.TTL/2 < interval {
+		interval = cfg.TTL / 2
+	}
+
+	server.reaper = newReaper(handler, interval)
+
+	return server
 }
 
 func (s *HTTPServer) Start() error {
-	r := initRouter()
+	// restore the topology saved on the last shutdown
+	if s.config.SnapshotPath != "" {
+		err := s.handler.loadSnapshot(s.config.SnapshotPath)
+		if err != nil {
+			return err
+		}
+	}
 
-	address := fmt.Sprintf("0.0.0.0:%d", 8080)
+	r := initRouter(s.handler)
 
 	server := &http.Server{
-		Addr:         address,
-		WriteTimeout: time.Second * 10,
-		ReadTimeout:  time.Second * 10,
-		IdleTimeout:  time.Second * 10,
+		Addr:         s.config.Address,
+		WriteTimeout: s.config.WriteTimeout,
+		ReadTimeout:  s.config.ReadTimeout,
+		IdleTimeout:  s.config.IdleTimeout,
 		Handler:      r,
 	}
 
@@ -33,23 +67,40 @@ func (s *HTTPServer) Start() error {
 
 	go s.listen()
 
-	log.Println("server started at 0.0.0.0:8080")
+	// evicts the silent nodes in the background
+	if s.reaper != nil {
+		s.reaper.Start()
+	}
+
+	s.handler.logger.Info("server started", logging.F("address", s.config.Address))
 
 	return nil
 }
 
 func (s HTTPServer) listen() {
 	err := s.server.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalln(err)
 	}
 }
 
 func (s HTTPServer) Shutdown(ctx context.Context) {
+	if s.reaper != nil {
+		s.reaper.Stop()
+	}
+
 	s.server.SetKeepAlivesEnabled(false)
 
 	err := s.server.Shutdown(ctx)
 	if err != nil {
 		log.Fatalln(err)
 	}
+
+	// save the topology to restore it on the next start
+	if s.config.SnapshotPath != "" {
+		err = s.handler.saveSnapshot(s.config.SnapshotPath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
 }
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// PeerRecord: a peer with its parent in the snapshot file. Parent is zero, if the peer is a root
+type PeerRecord struct {
+	Id       int     `json:"id"`
+	Capacity int     `json:"capacity"`
+	Parent   int     `json:"parent"`
+	Source   bool    `json:"source,omitempty"`
+	Region   string  `json:"region,omitempty"`
+	X        float64 `json:"x,omitempty"`
+	Y        float64 `json:"y,omitempty"`
+}
+
+// loadSnapshot: restores the topology of the network from the given file.
+// If the file does not exist, then there are no changes happen to the network
+func (hdl handler) loadSnapshot(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var peers []PeerRecord
+
+	err = json.Unmarshal(content, &peers)
+	if err != nil {
+		return err
+	}
+
+	records := make([]entities.PeerRecord, 0, len(peers))
+
+	for _, peer := range peers {
+		records = append(records, entities.PeerRecord{
+			Id:       peer.Id,
+			Capacity: peer.Capacity,
+			Parent:   peer.Parent,
+			Source:   peer.Source,
+			Location: entities.Location{Region: peer.Region, X: peer.X, Y: peer.Y},
+		})
+	}
+
+	snapshots, ok := hdl.usecase.Snapshots()
+	if !ok {
+		return entities.ErrUnsupported.Errorf("snapshots are not supported by the %s topology", hdl.topology)
+	}
+
+	return snapshots.Restore(records)
+}
+
+// saveSnapshot: saves the topology of the network to the given file
+func (hdl handler) saveSnapshot(path string) error {
+	snapshots, ok := hdl.usecase.Snapshots()
+	if !ok {
+		return entities.ErrUnsupported.Errorf("snapshots are not supported by the %s topology", hdl.topology)
+	}
+
+	records := snapshots.Snapshot()
+
+	peers := make([]PeerRecord, 0, len(records))
+
+	for _, record := range records {
+		peers = append(peers, PeerRecord{
+			Id:       record.Id,
+			Capacity: record.Capacity,
+			Parent:   record.Parent,
+			Source:   record.Source,
+			Region:   record.Location.Region,
+			X:        record.Location.X,
+			Y:        record.Location.Y,
+		})
+	}
+
+	content, err := json.Marshal(peers)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0644)
+}
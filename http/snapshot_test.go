@@ -0,0 +1,43 @@
+package http
+
+import (
+	"path/filepath"
+	"testing"
+
+	"p2p-network-simulator/config"
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	first := newHandler(config.Default())
+
+	// a missing file leaves the network empty
+	err := first.loadSnapshot(path)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	first.usecase.Join(entities.Node{Id: 1, Capacity: 1})
+	first.usecase.Join(entities.Node{Id: 2, Capacity: 0})
+
+	err = first.saveSnapshot(path)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	second := newHandler(config.Default())
+
+	err = second.loadSnapshot(path)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	expected := "1(1/1)[ 2(0/0) ]"
+
+	got := second.usecase.Trace()
+	if len(got) != 1 || got[0] != expected {
+		t.Errorf("expected [%s], but got %v", expected, got)
+	}
+}
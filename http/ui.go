@@ -0,0 +1,18 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// ui: self contained page which draws the network and updates it from the event stream
+//
+//go:embed ui/index.html
+var ui []byte
+
+// UI: controller for get the web page of the simulator
+func (hdl handler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(ui)
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUI(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/ui", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	h.UI(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v, but got %v", http.StatusOK, rr.Code)
+	}
+
+	if rr.Header().Get("content-type") != "text/html; charset=utf-8" {
+		t.Errorf("expected %v, but got %v", "text/html; charset=utf-8", rr.Header().Get("content-type"))
+	}
+
+	// the page only talks to the existing handlers
+	for _, path := range []string{`"/events"`, `"/trace"`, `"/stats"`, `"/join"`, `"/leave/"`, `"/merge"`} {
+		if !strings.Contains(rr.Body.String(), path) {
+			t.Errorf("expected the page to use %s", path)
+		}
+	}
+}
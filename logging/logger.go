@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level: severity of a log entry. A logger writes the entries at or above its level
+type Level int32
+
+const (
+	Debug Level = iota
+	Info
+	Error
+)
+
+// String: returns the name of the level
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Error:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// ParseLevel: returns the level for the given name
+func ParseLevel(name string) (Level, error) {
+	for _, l := range []Level{Debug, Info, Error} {
+		if l.String() == name {
+			return l, nil
+		}
+	}
+
+	return Info, fmt.Errorf("unknown log level %q", name)
+}
+
+// Field: a key value pair attached to a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F: creates a field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger: writes structured log entries, one per line, as JSON or logfmt
+type Logger struct {
+	out  io.Writer
+	json bool
+
+	// shared by the loggers derived with With, so the level can be changed at runtime
+	level *int32
+
+	// fields attached to every entry
+	fields []Field
+
+	// using mutex to prevent from interleaving entries
+	lock *sync.Mutex
+}
+
+// New: creates a logger writing to the given writer. format is either json or logfmt
+func New(out io.Writer, level Level, format string) *Logger {
+	l := int32(level)
+
+	return &Logger{
+		out:   out,
+		json:  format == "json",
+		level: &l,
+		lock:  &sync.Mutex{},
+	}
+}
+
+// Discard: creates a logger which writes nothing
+func Discard() *Logger {
+	return New(ioutil.Discard, Error+1, "logfmt")
+}
+
+// With: returns a logger which attaches the given fields to every entry.
+// The returned logger shares the level with its parent
+func (l *Logger) With(fields ...Field) *Logger {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	merged = append(merged, fields...)
+
+	return &Logger{
+		out:    l.out,
+		json:   l.json,
+		level:  l.level,
+		fields: merged,
+		lock:   l.lock,
+	}
+}
+
+// Level: returns the current level
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(l.level))
+}
+
+// SetLevel: changes the level of the logger and every logger derived from it
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(l.level, int32(level))
+}
+
+// Debug: writes a debug entry
+func (l *Logger) Debug(message string, fields ...Field) {
+	l.write(Debug, message, fields)
+}
+
+// Info: writes an info entry
+func (l *Logger) Info(message string, fields ...Field) {
+	l.write(Info, message, fields)
+}
+
+// Error: writes an error entry
+func (l *Logger) Error(message string, fields ...Field) {
+	l.write(Error, message, fields)
+}
+
+// write: encodes and writes an entry, if the level is enabled
+func (l *Logger) write(level Level, message string, fields []Field) {
+	if level < l.Level() {
+		return
+	}
+
+	all := make([]Field, 0, 3+len(l.fields)+len(fields))
+	all = append(all, F("time", time.Now().UTC().Format(time.RFC3339Nano)), F("level", level.String()), F("msg", message))
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+
+	var line []byte
+
+	if l.json {
+		line = encodeJSON(all)
+	} else {
+		line = encodeLogfmt(all)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.out.Write(line)
+}
+
+// encodeJSON: encodes the fields as a JSON object, keeping the order of the fields
+func encodeJSON(fields []Field) []byte {
+	var buffer bytes.Buffer
+
+	buffer.WriteByte('{')
+
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(field.Key)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+
+		value, err := json.Marshal(jsonValue(field.Value))
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(field.Value))
+		}
+
+		buffer.Write(value)
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.Bytes()
+}
+
+// jsonValue: converts the values which do not encode well to JSON
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	}
+
+	return value
+}
+
+// encodeLogfmt: encodes the fields as key=value pairs
+func encodeLogfmt(fields []Field) []byte {
+	var buffer bytes.Buffer
+
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(' ')
+		}
+
+		buffer.WriteString(field.Key)
+		buffer.WriteByte('=')
+
+		value := fmt.Sprint(field.Value)
+
+		// quote the values which contain spaces, quotes or equal signs
+		if value == "" || strings.ContainsAny(value, " \"=\t\n") {
+			value = strconv.Quote(value)
+		}
+
+		buffer.WriteString(value)
+	}
+
+	buffer.WriteByte('\n')
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	testTable := []struct {
+		name          string
+		input         string
+		expected      Level
+		expectedError bool
+	}{
+		{name: "debug", input: "debug", expected: Debug},
+		{name: "info", input: "info", expected: Info},
+		{name: "error", input: "error", expected: Error},
+		{name: "unknown", input: "warn", expected: Info, expectedError: true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := ParseLevel(testCase.input)
+
+			if result != testCase.expected {
+				t.Errorf("expected %s, but got %s", testCase.expected, result)
+			}
+
+			if (err != nil) != testCase.expectedError {
+				t.Errorf("expected error %v, but got %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	testTable := []struct {
+		name     string
+		format   string
+		level    Level
+		write    func(l *Logger)
+		expected []string
+	}{
+		{
+			name:   "logfmt",
+			format: "logfmt",
+			level:  Info,
+			write: func(l *Logger) {
+				l.With(F("request_id", "abc")).Info("node joined", F("node", 1), F("route", "/join"))
+			},
+			expected: []string{`level=info msg="node joined" request_id=abc node=1 route=/join`},
+		},
+		{
+			name:   "json",
+			format: "json",
+			level:  Info,
+			write: func(l *Logger) {
+				l.Error("failed", F("err", errors.New("boom")), F("duration", time.Second), F("status", 422))
+			},
+			expected: []string{`"level":"error","msg":"failed","err":"boom","duration":"1s","status":422}`},
+		},
+		{
+			name:   "below the level",
+			format: "logfmt",
+			level:  Error,
+			write: func(l *Logger) {
+				l.Debug("debug")
+				l.Info("info")
+			},
+			expected: nil,
+		},
+		{
+			name:   "level changed at runtime",
+			format: "logfmt",
+			level:  Error,
+			write: func(l *Logger) {
+				child := l.With(F("component", "network"))
+
+				l.SetLevel(Debug)
+				child.Debug("visible")
+			},
+			expected: []string{`level=debug msg=visible component=network`},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+
+			testCase.write(New(&buffer, testCase.level, testCase.format))
+
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			if buffer.Len() == 0 {
+				lines = nil
+			}
+
+			if len(lines) != len(testCase.expected) {
+				t.Fatalf("expected %v, but got %v", testCase.expected, lines)
+			}
+
+			for i, line := range lines {
+				if !strings.Contains(line, testCase.expected[i]) {
+					t.Errorf("expected %s in %s", testCase.expected[i], line)
+				}
+			}
+		})
+	}
+}
@@ -2,19 +2,31 @@ package main
 
 import (
 	"context"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"p2p-network-simulator/config"
 	"p2p-network-simulator/http"
 )
 
 func main() {
 	ctx := context.Background()
 
-	httpServer := http.NewHTTPServer()
-	httpServer.Start()
+	// settings from the flags, environment variables and the config file
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	httpServer := http.NewHTTPServer(cfg)
+
+	err = httpServer.Start()
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	channel := make(chan os.Signal, 1)
 
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"sort"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+// SetCapacity: changes the max capacity of a node.
+// If the node has more children than the new capacity, then the children with the least free capacity are re-homed
+func (network *P2PNetwork) SetCapacity(id int, capacity int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if capacity < 0 {
+		return entities.ErrInvalidCapacity
+	}
+
+	peer, t := network.locate(id)
+	if peer == nil {
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	network.resize(peer, t, capacity)
+
+	// more capacity can take waiting peers
+	network.drain()
+
+	return nil
+}
+
+// resize: changes the max capacity of the peer and re-homes the children which do not fit anymore
+func (network *P2PNetwork) resize(peer *tree.Peer, t *tree.Tree, capacity int) {
+	if capacity == peer.MaxCapacity {
+		return
+	}
+
+	// keep the children with the most free capacity, like a leave keeps the child with the most capacity
+	sort.SliceStable(peer.Children, func(i, j int) bool {
+		return peer.Children[i].Capacity > peer.Children[j].Capacity
+	})
+
+	excess := make([]*tree.Peer, 0)
+	if len(peer.Children) > capacity {
+		excess = append(excess, peer.Children[capacity:]...)
+	}
+
+	for _, child := range excess {
+		peer.RemoveChild(child)
+	}
+
+	peer.MaxCapacity = capacity
+	peer.Capacity = capacity - len(peer.Children)
+
+	// update the peer in the treap. crashed peers stay out of it until they are repaired
+	network.treap.Delete(peer.Id)
+
+	network.insert(peer)
+
+	for _, child := range excess {
+		// delete child's tree peers from the treap
+		// to prevent from adding the child to its own tree
+		network.treap.DeepDelete(child)
+
+		// add to the network
+		network.reattach(child)
+
+		// the subtree is dropped or waits in the queue, if there is no place for it
+		if _, ok := network.ids[child.Id]; !ok {
+			continue
+		}
+
+		network.rehomed++
+
+		network.options.Logger.Debug("subtree rehomed", logging.F("node", child.Id), logging.F("resized", peer.Id))
+
+		// re insert the deleted child's tree peers
+		network.deepInsert(child)
+	}
+
+	// more free capacity can move the peer towards the root
+	network.reOrder(peer, t)
+}
+
+// disconnected: reports whether the peer is beneath a crashed peer which is not repaired yet
+func (network *P2PNetwork) disconnected(peer *tree.Peer) bool {
+	for current := peer.Parent; current != nil; current = current.Parent {
+		if _, ok := network.failed[current.Id]; ok {
+			return true
+		}
+	}
+
+	return false
+}
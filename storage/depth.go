@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+// parentFor: returns the peer with the most free capacity which can take the given peer (and its subtree)
+// without going deeper than the max depth. With stability, stable peers are returned before the newcomers,
+// and with locality, the closest peer is returned instead. nil, if there is no such peer
+func (network *P2PNetwork) parentFor(peer *tree.Peer) *tree.Peer {
+	if network.options.MaxDepth <= 0 && !network.options.Locality && network.options.StableAfter <= 0 {
+		return network.treap.Get()
+	}
+
+	fits := network.within(peer)
+
+	if compare := network.preference(peer); compare != nil {
+		return network.treap.GetClosest(fits, compare)
+	}
+
+	return network.treap.GetWhere(fits)
+}
+
+// within: returns a filter of the parents which keep the subtree of the given peer within the max depth
+func (network *P2PNetwork) within(peer *tree.Peer) func(parent *tree.Peer) bool {
+	height := tree.NewTree(peer).Height()
+
+	return func(parent *tree.Peer) bool {
+		return network.options.MaxDepth <= 0 || parent.Depth()+height <= network.options.MaxDepth
+	}
+}
+
+// preference: compares the parents for the given peer, by stability and then by locality.
+// nil, if neither of them is enabled
+func (network *P2PNetwork) preference(peer *tree.Peer) func(a *tree.Peer, b *tree.Peer) int {
+	compares := make([]func(a *tree.Peer, b *tree.Peer) int, 0)
+
+	if network.options.StableAfter > 0 {
+		compares = append(compares, network.steadier(time.Now()))
+	}
+
+	if network.options.Locality {
+		compares = append(compares, closer(peer))
+	}
+
+	if len(compares) == 0 {
+		return nil
+	}
+
+	return func(a *tree.Peer, b *tree.Peer) int {
+		for _, compare := range compares {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	}
+}
+
+// admit: adds the joining peer to the network. If there is no place for the peer, then the peer waits
+// in the queue, or an error is returned without a queue
+func (network *P2PNetwork) admit(peer *tree.Peer) (entities.Admission, error) {
+	err := network.place(peer)
+	if err == nil || network.options.Queue == NoQueue {
+		return entities.Admitted, err
+	}
+
+	network.enqueue(peer)
+
+	return entities.Queued, nil
+}
+
+// place: adds the peer to the network. With a max depth, if every peer with free capacity is too deep,
+// then the peer takes the place of a shallower peer. Returns an error, if there is no place for the peer
+func (network *P2PNetwork) place(peer *tree.Peer) error {
+	if network.parentFor(peer) != nil {
+		network.add(peer)
+
+		// a peer with more capacity than its parent moves towards the root
+		if network.options.Promote {
+			network.reOrder(peer, network.locateTree(peer))
+		}
+
+		return nil
+	}
+
+	// nobody has free capacity, so the peer starts a new tree, unless only sources can.
+	// with a queue, only the first peer of an empty network starts a tree
+	if network.treap.Get() == nil {
+		if network.options.SourcesOnly {
+			return entities.ErrNoCapacity.Errorf("no free capacity for node %d, only sources can start a tree", peer.Id)
+		}
+
+		if network.options.Queue != NoQueue && len(network.topology) > 0 {
+			return entities.ErrNoCapacity.Errorf("no free capacity for node %d", peer.Id)
+		}
+
+		network.add(peer)
+		return nil
+	}
+
+	// every peer with free capacity is too deep
+	if !network.displace(peer) {
+		return entities.ErrDepthExceeded.Errorf("node %d cannot join within the max depth %d", peer.Id, network.options.MaxDepth)
+	}
+
+	return nil
+}
+
+// placeable: returns the error place would return for the peer, without changing the network
+func (network *P2PNetwork) placeable(peer *tree.Peer) error {
+	if network.treap.Any(network.within(peer)) {
+		return nil
+	}
+
+	if !network.treap.Any(func(*tree.Peer) bool { return true }) {
+		if network.options.SourcesOnly {
+			return entities.ErrNoCapacity.Errorf("no free capacity for node %d, only sources can start a tree", peer.Id)
+		}
+
+		if network.options.Queue != NoQueue && len(network.topology) > 0 {
+			return entities.ErrNoCapacity.Errorf("no free capacity for node %d", peer.Id)
+		}
+
+		return nil
+	}
+
+	if peer.Capacity == 0 || len(peer.Children) > 0 || network.displaceable() == nil {
+		return entities.ErrDepthExceeded.Errorf("node %d cannot join within the max depth %d", peer.Id, network.options.MaxDepth)
+	}
+
+	return nil
+}
+
+/*
+displace: the joining peer takes the place of the shallowest peer which can move one level down,
+and the displaced peer becomes its child. Returns false, if the joining peer cannot have children
+or no peer can move down within the max depth
+
+	max depth 2
+
+	  1(2/2)                       1(2/2)
+	  /   \      join 5(0/1)       /   \
+	2(0/0) 3(1/1)  ------->     5(1/1) 3(1/1)
+	         |                    |      |
+	       4(0/1)               2(0/0) 4(0/1)
+*/
+func (network *P2PNetwork) displace(peer *tree.Peer) bool {
+	// a waiting subtree only joins beneath a peer
+	if peer.Capacity == 0 || len(peer.Children) > 0 {
+		return false
+	}
+
+	displaced := network.displaceable()
+	if displaced == nil {
+		return false
+	}
+
+	parent := displaced.Parent
+
+	// the parent keeps the same number of children, so it stays as it is in the treap
+	parent.RemoveChild(displaced)
+	parent.AddChild(peer)
+	peer.AddChild(displaced)
+
+	if peer.Capacity > 0 {
+		network.treap.Insert(peer)
+	}
+
+	network.options.Logger.Debug("peer displaced", logging.F("node", displaced.Id), logging.F("by", peer.Id))
+
+	// more free capacity than the parent can move the peer towards the root
+	network.reOrder(peer, network.locateTree(peer))
+
+	return true
+}
+
+// displaceable: returns the shallowest peer which can move one level down within the max depth,
+// the lowest id on ties. nil, if no peer can
+func (network *P2PNetwork) displaceable() *tree.Peer {
+	var displaced *tree.Peer
+	depth := 0
+
+	for _, t := range network.topology {
+		for _, candidate := range t.Peers() {
+			// roots stay as they are, and crashed subtrees are not touched until they are repaired
+			if candidate.Parent == nil || network.disconnected(candidate) {
+				continue
+			}
+
+			if _, ok := network.failed[candidate.Id]; ok {
+				continue
+			}
+
+			d := candidate.Depth()
+
+			if d+tree.NewTree(candidate).Height() > network.options.MaxDepth {
+				continue
+			}
+
+			if displaced == nil || d < depth || (d == depth && candidate.Id < displaced.Id) {
+				displaced = candidate
+				depth = d
+			}
+		}
+	}
+
+	return displaced
+}
+
+// reorderFits: reports whether the peer can swap places with its parent without pushing
+// the other children of the parent deeper than the max depth
+func (network *P2PNetwork) reorderFits(peer *tree.Peer) bool {
+	if network.options.MaxDepth <= 0 {
+		return true
+	}
+
+	// the parent moves one level down, and its other children with it
+	depth := peer.Parent.Depth() + 1
+
+	for _, sibling := range peer.Parent.Children {
+		if sibling == peer {
+			continue
+		}
+
+		if depth+tree.NewTree(sibling).Height() > network.options.MaxDepth {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestMaxDepth(t *testing.T) {
+	// 1(0/2), 2(0/0), 3(0/1), 4(0/1), 5(0/1) and 6(0/3) join in order
+	nodes := []entities.Node{
+		{Id: 1, Capacity: 2},
+		{Id: 2, Capacity: 0},
+		{Id: 3, Capacity: 1},
+		{Id: 4, Capacity: 1},
+		{Id: 5, Capacity: 1},
+		{Id: 6, Capacity: 3},
+	}
+
+	testTable := []struct {
+		name          string
+		maxDepth      int
+		join          int // number of nodes which join
+		expected      []string
+		expectedError error
+	}{
+		{
+			name:     "no limit",
+			maxDepth: 0,
+			join:     6,
+			expected: []string{"1(2/2)[ 2(0/0) 3(1/1)[ 4(1/1)[ 5(1/1)[ 6(0/3) ] ] ] ]"},
+		},
+		{
+			name:     "joins within the max depth",
+			maxDepth: 2,
+			join:     4,
+			expected: []string{"1(2/2)[ 2(0/0) 3(1/1)[ 4(0/1) ] ]"},
+		},
+		{
+			/*
+					1				1
+				  /   \\			  /   \\
+				 2     3	->	 3     5
+					   |		 |     |
+					   4		 4     2
+			*/
+			name:     "takes the place of a shallower peer",
+			maxDepth: 2,
+			join:     5,
+			expected: []string{"1(2/2)[ 3(1/1)[ 4(0/1) ] 5(1/1)[ 2(0/0) ] ]"},
+		},
+		{
+			name:          "rejected",
+			maxDepth:      2,
+			join:          6,
+			expected:      []string{"1(2/2)[ 3(1/1)[ 4(0/1) ] 5(1/1)[ 2(0/0) ] ]"},
+			expectedError: entities.ErrDepthExceeded,
+		},
+		{
+			name:          "rejected without children",
+			maxDepth:      1,
+			join:          4,
+			expected:      []string{"1(2/2)[ 2(0/0) 3(0/1) ]"},
+			expectedError: entities.ErrDepthExceeded,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{MaxDepth: testCase.maxDepth}).(*P2PNetwork)
+
+			var err error
+
+			for _, node := range nodes[:testCase.join] {
+				_, err = network.Join(node)
+			}
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestMaxDepthLeave(t *testing.T) {
+	testTable := []struct {
+		name     string
+		maxDepth int
+		expected []string
+	}{
+		{
+			name:     "no limit",
+			maxDepth: 0,
+			expected: []string{"3(1/1)[ 4(1/1)[ 5(1/1)[ 2(0/0) ] ] ]"},
+		},
+		{
+			// beneath 4, the subtree of 5 would be too deep, so it becomes a tree
+			name:     "re-homed subtree stays within the max depth",
+			maxDepth: 2,
+			expected: []string{"3(1/1)[ 4(0/1) ]", "5(1/1)[ 2(0/0) ]"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{MaxDepth: 2})
+
+			// 1(2/2)[ 3(1/1)[ 4(0/1) ] 5(1/1)[ 2(0/0) ] ]
+			for _, node := range []entities.Node{{Id: 1, Capacity: 2}, {Id: 2}, {Id: 3, Capacity: 1}, {Id: 4, Capacity: 1}, {Id: 5, Capacity: 1}} {
+				network.Join(node)
+			}
+
+			// the limit only changes the restructuring of the leave
+			network.(*P2PNetwork).options.MaxDepth = testCase.maxDepth
+
+			if _, err := network.Leave(1); err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+
+			if stats := network.Stats(); testCase.maxDepth > 0 && stats.MaxDepth > testCase.maxDepth {
+				t.Errorf("expected %v, but got %v", testCase.maxDepth, stats.MaxDepth)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import "p2p-network-simulator/domain/entities"
+
+// maxDisruptions: number of the latest disruption records which are kept
+const maxDisruptions = 1000
+
+// disruptions: running totals of the disruptions of the leaves, with the records of the latest ones
+type disruptions struct {
+	totals  entities.DisruptionStats
+	records []entities.Disruption
+}
+
+// record: adds the disruption of a leave to the totals and the records
+func (d *disruptions) record(disruption entities.Disruption) {
+	d.totals.Leaves++
+	d.totals.ChangedParent += disruption.ChangedParent
+	d.totals.Rehomed += disruption.Rehomed
+	d.totals.DepthChange += disruption.DepthChange
+
+	d.records = append(d.records, disruption)
+
+	if len(d.records) > maxDisruptions {
+		d.records = d.records[len(d.records)-maxDisruptions:]
+	}
+}
+
+// stats: returns the totals with a copy of the records
+func (d disruptions) stats() entities.DisruptionStats {
+	stats := d.totals
+	stats.Records = make([]entities.Disruption, len(d.records))
+	copy(stats.Records, d.records)
+
+	return stats
+}
+
+// Disruption: returns the running totals of the disruptions of the leaves, with the records of the latest ones
+func (network *P2PNetwork) Disruption() entities.DisruptionStats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	return network.disruptions.stats()
+}
+
+// maxDepth: depth of the deepest peer of the network, roots have zero depth
+func (network *P2PNetwork) maxDepth() int {
+	depth := 0
+
+	for _, t := range network.topology {
+		if d := t.Height() - 1; d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// joinDisruption: joins the peers of the disruption tests
+//
+//	  1
+//	 / \
+//	2   3
+//	|   |
+//	4   5
+func joinDisruption(network *P2PNetwork) {
+	for index, capacity := range []int{2, 1, 1, 0, 0} {
+		network.Join(entities.Node{Id: index + 1, Capacity: capacity})
+	}
+}
+
+func TestDisruption(t *testing.T) {
+	testTable := []struct {
+		name     string
+		leave    int
+		expected entities.Disruption
+	}{
+		{
+			name:     "leaf",
+			leave:    5,
+			expected: entities.Disruption{Id: 5, ChangedParent: 0, Rehomed: 0, DepthChange: 0},
+		},
+		{
+			name:     "peer with a single child",
+			leave:    2,
+			expected: entities.Disruption{Id: 2, ChangedParent: 1, Rehomed: 0, DepthChange: 0},
+		},
+		{
+			// 2 becomes the root without free capacity, so 3 is re homed as a tree of its own
+			name:     "root with many children",
+			leave:    1,
+			expected: entities.Disruption{Id: 1, ChangedParent: 2, Rehomed: 1, DepthChange: -1},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetwork().(*P2PNetwork)
+
+			joinDisruption(network)
+
+			report, err := network.Leave(testCase.leave)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			if report.Disruption != testCase.expected {
+				t.Errorf("expected %+v, but got %+v", testCase.expected, report.Disruption)
+			}
+		})
+	}
+}
+
+func TestDisruptionTotals(t *testing.T) {
+	network := NewP2PNetwork().(*P2PNetwork)
+
+	joinDisruption(network)
+
+	for _, id := range []int{5, 2, 1} {
+		network.Leave(id)
+	}
+
+	// a leave which fails is not recorded
+	network.Leave(9)
+
+	records := []entities.Disruption{
+		{Id: 5, ChangedParent: 0, Rehomed: 0, DepthChange: 0},
+		{Id: 2, ChangedParent: 1, Rehomed: 0, DepthChange: -1},
+		{Id: 1, ChangedParent: 2, Rehomed: 1, DepthChange: 0},
+	}
+
+	stats := network.Disruption()
+
+	if stats.Leaves != 3 || stats.ChangedParent != 3 || stats.Rehomed != 1 || stats.DepthChange != -1 {
+		t.Errorf("expected %v, but got %+v", "3 leaves, 3 changed parents, 1 re homed and -1 depth change", stats)
+	}
+
+	if len(stats.Records) != len(records) {
+		t.Fatalf("expected %v, but got %v", records, stats.Records)
+	}
+
+	for i := range records {
+		if stats.Records[i] != records[i] {
+			t.Errorf("expected %+v, but got %+v", records[i], stats.Records[i])
+		}
+	}
+}
+
+func TestDisruptionRecords(t *testing.T) {
+	var d disruptions
+
+	for id := 1; id <= maxDisruptions+1; id++ {
+		d.record(entities.Disruption{Id: id, ChangedParent: 1})
+	}
+
+	stats := d.stats()
+
+	// the totals count every leave, the records keep the latest ones
+	if stats.Leaves != maxDisruptions+1 || stats.ChangedParent != maxDisruptions+1 {
+		t.Errorf("expected %v, but got %v", maxDisruptions+1, stats.Leaves)
+	}
+
+	if len(stats.Records) != maxDisruptions || stats.Records[0].Id != 2 {
+		t.Errorf("expected %v, but got %v", 2, stats.Records[0].Id)
+	}
+}
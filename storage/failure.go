@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+// Fail: a node crashing without leaving the network.
+// The subtree of the node stays disconnected until the crash is detected, then it is repaired like a leave
+func (network *P2PNetwork) Fail(id int) (entities.FailureReport, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	// locate the peer and the tree for the given id
+	peer, t := network.locate(id)
+
+	// if the given id is not in the topology, then return an error
+	if peer == nil {
+		return entities.FailureReport{}, entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	if _, ok := network.failed[id]; ok {
+		return entities.FailureReport{}, entities.ErrAlreadyFailed.Errorf("id %d already failed", id)
+	}
+
+	now := time.Now()
+	delay := network.options.DetectionDelay
+
+	report := entities.FailureReport{
+		Id:           id,
+		Disconnected: len(tree.NewTree(peer).Peers()) - 1,
+		FailedAt:     now,
+		RepairAt:     now.Add(delay),
+	}
+
+	// nobody can join beneath the crashed peer or its disconnected subtree
+	network.failed[id] = now
+	network.treap.DeepDelete(peer)
+
+	s := network.sessions[id]
+	s.failures++
+	network.sessions[id] = s
+
+	if delay <= 0 {
+		before := network.checkpoint()
+		network.leave(peer, t)
+		network.commit(before)
+
+		return report, nil
+	}
+
+	// the time of the crash tells this crash apart from a later crash of a peer with the same id
+	time.AfterFunc(delay, func() {
+		network.repair(id, now)
+	})
+
+	return report, nil
+}
+
+// repair: removes the crashed peer for the given id, once the crash at the given time is detected.
+// If the peer already left the network, or it crashed again since then, then there are no changes happen
+func (network *P2PNetwork) repair(id int, failedAt time.Time) {
+	report, ok := network.repairLocked(id, failedAt)
+	if !ok {
+		return
+	}
+
+	network.options.Logger.Info("crash repaired", logging.F("node", id), logging.F("reassigned", len(report.Reassignments)))
+
+	// outside of the lock, so the callback can read the network
+	if network.options.OnRepair != nil {
+		network.options.OnRepair(report)
+	}
+}
+
+// repairLocked: removes the crashed peer under the lock. returns false, if there is nothing to repair
+// or the peer is failed by a later crash, which is repaired by its own timer
+func (network *P2PNetwork) repairLocked(id int, failedAt time.Time) (entities.LeaveReport, bool) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if at, ok := network.failed[id]; !ok || !at.Equal(failedAt) {
+		return entities.LeaveReport{}, false
+	}
+
+	peer, t := network.locate(id)
+	if peer == nil {
+		delete(network.failed, id)
+		return entities.LeaveReport{}, false
+	}
+
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	return network.leave(peer, t), true
+}
+
+// connected: reports whether the peer can take children, since it did not crash and it is not beneath a crashed peer
+func (network *P2PNetwork) connected(peer *tree.Peer) bool {
+	if _, ok := network.failed[peer.Id]; ok {
+		return false
+	}
+
+	return !network.disconnected(peer)
+}
+
+// insert: inserts the peer into the treap, if it has free capacity and it is connected
+func (network *P2PNetwork) insert(peer *tree.Peer) {
+	if peer.Capacity > 0 && network.connected(peer) {
+		network.treap.Insert(peer)
+	}
+}
+
+// deepInsert: inserts the peers of the given subtree which have free capacity into the treap.
+// Crashed peers and their subtrees stay out of it until they are repaired
+func (network *P2PNetwork) deepInsert(peer *tree.Peer) {
+	if _, ok := network.failed[peer.Id]; ok {
+		return
+	}
+
+	if peer.Capacity > 0 {
+		network.treap.Insert(peer)
+	}
+
+	for _, child := range peer.Children {
+		network.deepInsert(child)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestFail(t *testing.T) {
+	testTable := []struct {
+		name                 string
+		delay                time.Duration
+		id                   int
+		expectedDisconnected int
+		expectedWhileFailed  []string
+		expected             []string
+		expectedError        error
+	}{
+		{
+			/*
+					3				7
+				  / | \\				|
+				 4  5  6   ---->	3
+					|			   / \\
+					7			  4   6
+			*/
+			name:                 "detected immediately",
+			delay:                0,
+			id:                   5,
+			expectedDisconnected: 1,
+			expectedWhileFailed:  []string{"7(1/5)[ 3(2/3)[ 4(0/0) 6(0/0) ] ]"},
+			expected:             []string{"7(1/5)[ 3(2/3)[ 4(0/0) 6(0/0) ] ]"},
+			expectedError:        nil,
+		},
+		{
+			name:                 "detected after a delay",
+			delay:                time.Millisecond * 20,
+			id:                   5,
+			expectedDisconnected: 1,
+			expectedWhileFailed:  []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			expected:             []string{"7(1/5)[ 3(2/3)[ 4(0/0) 6(0/0) ] ]"},
+			expectedError:        nil,
+		},
+		{
+			name:                 "not exists node",
+			delay:                0,
+			id:                   2,
+			expectedDisconnected: 0,
+			expectedWhileFailed:  []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			expected:             []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			expectedError:        errors.New("cannot locate id 2 node"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{DetectionDelay: testCase.delay}).(*P2PNetwork)
+
+			for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+				network.Join(node)
+			}
+
+			report, err := network.Fail(testCase.id)
+
+			if err == nil && testCase.expectedError != nil {
+				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), err)
+			}
+
+			if err != nil && testCase.expectedError == nil {
+				t.Errorf("expected %v, but got %s", testCase.expectedError, err.Error())
+			}
+
+			if report.Disconnected != testCase.expectedDisconnected {
+				t.Errorf("expected %d, but got %d", testCase.expectedDisconnected, report.Disconnected)
+			}
+
+			if report.RepairAt.Sub(report.FailedAt) != testCase.delay {
+				t.Errorf("expected %v, but got %v", testCase.delay, report.RepairAt.Sub(report.FailedAt))
+			}
+
+			assertTrace(t, network.Trace(), testCase.expectedWhileFailed)
+
+			time.Sleep(testCase.delay * 3)
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestFailTwice(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{DetectionDelay: time.Minute}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5} {
+		network.Join(node)
+	}
+
+	_, err := network.Fail(5)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	_, err = network.Fail(5)
+	if err == nil || err.Error() != "id 5 already failed" {
+		t.Errorf("expected id 5 already failed, but got %v", err)
+	}
+
+	// nobody joins beneath the crashed peer
+	network.Join(n6)
+
+	assertTrace(t, network.Trace(), []string{"3(3/3)[ 4(0/0) 5(0/1) 6(0/0) ]"})
+
+	// the crashed peer can still leave before the crash is detected
+	_, err = network.Leave(5)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	assertTrace(t, network.Trace(), []string{"3(2/3)[ 4(0/0) 6(0/0) ]"})
+}
+
+func TestOnRepair(t *testing.T) {
+	repaired := make(chan entities.LeaveReport, 1)
+
+	network := NewP2PNetworkWithOptions(Options{
+		DetectionDelay: time.Millisecond * 10,
+		OnRepair: func(report entities.LeaveReport) {
+			repaired <- report
+		},
+	}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5} {
+		network.Join(node)
+	}
+
+	_, err := network.Fail(5)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	select {
+	case report := <-repaired:
+		if report.Id != 5 {
+			t.Errorf("expected %d, but got %d", 5, report.Id)
+		}
+
+		// the network is not locked while the callback runs
+		assertTrace(t, network.Trace(), []string{"3(1/3)[ 4(0/0) ]"})
+
+	case <-time.After(time.Second):
+		t.Fatal("expected the repair to be reported")
+	}
+}
+
+// assertTrace: compares the given trace with the expected trace
+func assertTrace(t *testing.T, got []string, expected []string) {
+	t.Helper()
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("expected %s, but got %s", expected[i], got[i])
+		}
+	}
+}
+
+func TestLeaveWhileFailed(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{DetectionDelay: time.Hour}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{
+		{Id: 1, Capacity: 1},
+		{Id: 2, Capacity: 3},
+		{Id: 3, Capacity: 0},
+		{Id: 4, Capacity: 0},
+	} {
+		network.Join(node)
+	}
+
+	_, err := network.Fail(2)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	// the crashed peer gets free capacity, but it is not reordered and nobody joins beneath it
+	_, err = network.Leave(3)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	network.Join(entities.Node{Id: 5, Capacity: 0})
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 2(1/3)[ 4(0/0) ] ]", "5(0/0)"})
+}
+
+func TestFailAgain(t *testing.T) {
+	// the timers do not fire during the test, so the repairs of both crashes are run here in their place
+	network := NewP2PNetworkWithOptions(Options{DetectionDelay: time.Hour}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5} {
+		network.Join(node)
+	}
+
+	network.Fail(5)
+	first := network.failed[5]
+
+	network.Leave(5)
+	network.Join(n5)
+
+	// the second crash happens at a later time than the first one
+	time.Sleep(time.Millisecond)
+
+	_, err := network.Fail(5)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	second := network.failed[5]
+
+	// the timer of the first crash does not repair the second one
+	network.repair(5, first)
+
+	assertTrace(t, network.Trace(), []string{"3(2/3)[ 4(0/0) 5(0/1) ]"})
+
+	network.repair(5, second)
+
+	assertTrace(t, network.Trace(), []string{"3(1/3)[ 4(0/0) ]"})
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"sort"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// Graph: returns the peers of the network as nodes, and the links between parents and children as edges
+func (network *P2PNetwork) Graph() entities.Graph {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	graph := entities.Graph{
+		Nodes: make([]entities.GraphNode, 0, len(network.ids)),
+		Edges: make([]entities.GraphEdge, 0, len(network.ids)),
+	}
+
+	for _, t := range network.topology {
+		for _, peer := range t.Peers() {
+			_, source := network.sources[peer.Id]
+
+			graph.Nodes = append(graph.Nodes, entities.GraphNode{
+				Id:       peer.Id,
+				Capacity: peer.MaxCapacity,
+				Used:     len(peer.Children),
+				Depth:    peer.Depth(),
+				Source:   source,
+			})
+
+			for _, child := range peer.Children {
+				graph.Edges = append(graph.Edges, entities.GraphEdge{Parent: peer.Id, Child: child.Id})
+			}
+		}
+	}
+
+	sortGraph(graph)
+
+	return graph
+}
+
+// sortGraph: sorts the nodes by depth then id, and the edges by parent then child
+func sortGraph(graph entities.Graph) {
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Depth != graph.Nodes[j].Depth {
+			return graph.Nodes[i].Depth < graph.Nodes[j].Depth
+		}
+
+		return graph.Nodes[i].Id < graph.Nodes[j].Id
+	})
+
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Parent != graph.Edges[j].Parent {
+			return graph.Edges[i].Parent < graph.Edges[j].Parent
+		}
+
+		return graph.Edges[i].Child < graph.Edges[j].Child
+	})
+}
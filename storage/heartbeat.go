@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// Heartbeat: a node tells the network it is still alive
+func (network *P2PNetwork) Heartbeat(id int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.ids[id]; !ok {
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	network.heartbeats[id] = time.Now()
+
+	return nil
+}
+
+// Evict: removes the nodes without a heartbeat within the TTL of the network, like they left.
+// Returns the leave report of each evicted node
+func (network *P2PNetwork) Evict() []entities.LeaveReport {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	// without a ttl no node expires, so there is no version to record
+	if network.options.TTL <= 0 {
+		return make([]entities.LeaveReport, 0)
+	}
+
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	return network.evict(time.Now())
+}
+
+// evict: removes the nodes which have the last heartbeat before now - TTL
+func (network *P2PNetwork) evict(now time.Time) []entities.LeaveReport {
+	reports := make([]entities.LeaveReport, 0)
+
+	if network.options.TTL <= 0 {
+		return reports
+	}
+
+	expired := make([]int, 0)
+
+	for id, heartbeat := range network.heartbeats {
+		// crashed peers are removed when the crash is detected
+		if _, ok := network.failed[id]; ok {
+			continue
+		}
+
+		if now.Sub(heartbeat) > network.options.TTL {
+			expired = append(expired, id)
+		}
+	}
+
+	// evict in the order of ids, so the result does not depend on the map order
+	sort.Ints(expired)
+
+	for _, id := range expired {
+		peer, t := network.locate(id)
+		if peer == nil {
+			delete(network.heartbeats, id)
+			continue
+		}
+
+		reports = append(reports, network.leave(peer, t))
+	}
+
+	return reports
+}
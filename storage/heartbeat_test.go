@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestHeartbeat(t *testing.T) {
+	network := NewP2PNetwork().(*P2PNetwork)
+
+	network.Join(n1)
+
+	testTable := []struct {
+		name          string
+		id            int
+		expectedError error
+	}{
+		{
+			name:          "happy case",
+			id:            1,
+			expectedError: nil,
+		},
+		{
+			name:          "not exists node",
+			id:            2,
+			expectedError: errors.New("cannot locate id 2 node"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := network.Heartbeat(testCase.id)
+
+			if err == nil && testCase.expectedError != nil {
+				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), err)
+			}
+
+			if err != nil && testCase.expectedError == nil {
+				t.Errorf("expected %v, but got %s", testCase.expectedError, err.Error())
+			}
+
+			if err != nil && testCase.expectedError != nil && err.Error() != testCase.expectedError.Error() {
+				t.Errorf("expected %s, but got %s", testCase.expectedError.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func Test_evict(t *testing.T) {
+	testTable := []struct {
+		name            string
+		ttl             time.Duration
+		after           time.Duration
+		alive           []int
+		expectedEvicted []int
+		expected        []string
+	}{
+		{
+			name:            "eviction disabled",
+			ttl:             0,
+			after:           time.Hour,
+			alive:           nil,
+			expectedEvicted: []int{},
+			expected:        []string{"3(2/3)[ 4(0/0) 5(0/1) ]"},
+		},
+		{
+			name:            "nobody expired",
+			ttl:             time.Minute,
+			after:           time.Second,
+			alive:           nil,
+			expectedEvicted: []int{},
+			expected:        []string{"3(2/3)[ 4(0/0) 5(0/1) ]"},
+		},
+		{
+			name:            "silent peers expired",
+			ttl:             time.Minute,
+			after:           time.Minute * 2,
+			alive:           []int{3},
+			expectedEvicted: []int{4, 5},
+			expected:        []string{"3(0/3)"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{TTL: testCase.ttl}).(*P2PNetwork)
+
+			for _, node := range []entities.Node{n3, n4, n5} {
+				network.Join(node)
+			}
+
+			now := time.Now().Add(testCase.after)
+
+			for _, id := range testCase.alive {
+				network.heartbeats[id] = now
+			}
+
+			reports := network.evict(now)
+
+			if len(reports) != len(testCase.expectedEvicted) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedEvicted, reports)
+			}
+
+			for i, report := range reports {
+				if report.Id != testCase.expectedEvicted[i] {
+					t.Errorf("expected %d, but got %d", testCase.expectedEvicted[i], report.Id)
+				}
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
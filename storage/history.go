@@ -0,0 +1,381 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/treap"
+	"p2p-network-simulator/storage/tree"
+)
+
+// position: where a peer is in the topology. Positions of every peer are enough to rebuild the topology
+type position struct {
+	Capacity int
+	Parent   int // zero, if the peer is a root
+	Index    int // index among the children of the parent, or among the trees for roots
+	Source   bool
+	Location entities.Location
+}
+
+/*
+change: reversible record of an operation. Keeps the positions of only the peers the operation moved,
+before and after it. A peer missing in before joined with the operation, a peer missing in after left.
+The waiting peers are kept on both sides too, if the operation changed the queue.
+
+	undo: apply before		redo: apply after
+*/
+type change struct {
+	before map[int]position
+	after  map[int]position
+
+	queued      bool
+	queueBefore []waiting
+	queueAfter  []waiting
+}
+
+// state: the positions and the waiting peers before an operation, which the operation is recorded against
+type state struct {
+	positions map[int]position
+	queue     []waiting
+}
+
+// history: bounded list of changes. Changes before the cursor are applied, the ones after it can be redone
+type history struct {
+	changes []change
+	cursor  int
+
+	// version of the topology before the first change. version zero is the empty network
+	offset int
+}
+
+// version: returns the version of the current topology
+func (h *history) version() int {
+	return h.offset + h.cursor
+}
+
+// positions: returns the position of every peer in the topology
+func (network *P2PNetwork) positions() map[int]position {
+	positions := make(map[int]position, len(network.ids))
+
+	for index, t := range network.topology {
+		root := t.GetRoot()
+		_, source := network.sources[root.Id]
+		positions[root.Id] = position{Capacity: root.MaxCapacity, Index: index, Source: source, Location: root.Location}
+
+		for _, peer := range t.Peers() {
+			for i, child := range peer.Children {
+				positions[child.Id] = position{Capacity: child.MaxCapacity, Parent: peer.Id, Index: i, Location: child.Location}
+			}
+		}
+	}
+
+	return positions
+}
+
+// checkpoint: returns the positions to record the next operation against. nil, if the history is disabled
+func (network *P2PNetwork) checkpoint() *state {
+	if network.options.HistorySize <= 0 {
+		return nil
+	}
+
+	return &state{positions: network.positions(), queue: copyQueue(network.queue)}
+}
+
+// commit: records the difference between the checkpoint and the current topology as a change.
+// Drops the changes which could be redone, and the oldest change once the history is full
+func (network *P2PNetwork) commit(checkpoint *state) {
+	if checkpoint == nil {
+		return
+	}
+
+	before := checkpoint.positions
+	after := network.positions()
+
+	c := change{
+		before: make(map[int]position),
+		after:  make(map[int]position),
+	}
+
+	if !sameQueue(checkpoint.queue, network.queue) {
+		c.queued = true
+		c.queueBefore = checkpoint.queue
+		c.queueAfter = copyQueue(network.queue)
+	}
+
+	for id, p := range before {
+		if q, ok := after[id]; !ok || q != p {
+			c.before[id] = p
+		}
+	}
+
+	for id, p := range after {
+		if q, ok := before[id]; !ok || q != p {
+			c.after[id] = p
+		}
+	}
+
+	// nothing moved
+	if len(c.before) == 0 && len(c.after) == 0 && !c.queued {
+		return
+	}
+
+	h := &network.history
+
+	h.changes = append(h.changes[:h.cursor], c)
+	h.cursor++
+
+	if len(h.changes) > network.options.HistorySize {
+		h.changes = h.changes[1:]
+		h.cursor--
+		h.offset++
+	}
+}
+
+// apply: moves the peers of the change to the given positions
+func apply(positions map[int]position, moved map[int]position, other map[int]position) {
+	// peers which are only in the other side did not exist on this side
+	for id := range other {
+		if _, ok := moved[id]; !ok {
+			delete(positions, id)
+		}
+	}
+
+	for id, p := range moved {
+		positions[id] = p
+	}
+}
+
+// build: rebuilds the trees from the positions of the peers. Returns the ids of the sources too
+func build(positions map[int]position) ([]*tree.Tree, map[int]*tree.Peer, map[int]struct{}) {
+	sources := make(map[int]struct{})
+
+	peers := make(map[int]*tree.Peer, len(positions))
+	children := make(map[int][]int)
+	roots := make([]int, 0)
+
+	for id, p := range positions {
+		peers[id] = tree.NewPeer(entities.Node{Id: id, Capacity: p.Capacity, Location: p.Location})
+
+		if p.Source {
+			sources[id] = struct{}{}
+		}
+
+		if p.Parent == 0 {
+			roots = append(roots, id)
+		} else {
+			children[p.Parent] = append(children[p.Parent], id)
+		}
+	}
+
+	byIndex := func(ids []int) {
+		sort.Slice(ids, func(i, j int) bool {
+			return positions[ids[i]].Index < positions[ids[j]].Index
+		})
+	}
+
+	for parent, ids := range children {
+		byIndex(ids)
+
+		for _, id := range ids {
+			peers[parent].AddChild(peers[id])
+		}
+	}
+
+	byIndex(roots)
+
+	topology := make([]*tree.Tree, 0, len(roots))
+	for _, id := range roots {
+		topology = append(topology, tree.NewTree(peers[id]))
+	}
+
+	return topology, peers, sources
+}
+
+// reset: replaces the topology with the one at the given positions. Keeps the heartbeats and the crashes
+// of the peers which are still in the network. The sessions are left to the operations which add and remove peers
+func (network *P2PNetwork) reset(positions map[int]position) {
+	topology, peers, sources := build(positions)
+
+	network.topology = topology
+	network.sources = sources
+	network.treap = treap.NewTreapWithTieBreak(network.options.TieBreak, network.options.Seed)
+	network.ids = make(map[int]struct{}, len(peers))
+
+	now := time.Now()
+
+	for id, peer := range peers {
+		network.ids[id] = struct{}{}
+
+		if _, ok := network.heartbeats[id]; !ok {
+			network.heartbeats[id] = now
+		}
+
+		if peer.Capacity > 0 {
+			network.treap.Insert(peer)
+		}
+	}
+
+	for id := range network.heartbeats {
+		if _, ok := peers[id]; !ok {
+			delete(network.heartbeats, id)
+		}
+	}
+
+	// nobody can join beneath the crashed peers until they are repaired
+	for id := range network.failed {
+		if peer, ok := peers[id]; ok {
+			network.treap.DeepDelete(peer)
+		}
+	}
+}
+
+// Undo: reverts the last operation. Returns the version of the topology after the undo
+func (network *P2PNetwork) Undo() (int, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	h := &network.history
+
+	if h.cursor == 0 {
+		return h.version(), entities.ErrNothingToUndo
+	}
+
+	h.cursor--
+
+	c := h.changes[h.cursor]
+
+	positions := network.positions()
+	apply(positions, c.before, c.after)
+
+	network.reset(positions)
+
+	if c.queued {
+		network.queue = copyQueue(c.queueBefore)
+	}
+
+	return h.version(), nil
+}
+
+// Redo: applies the last reverted operation again. Returns the version of the topology after the redo
+func (network *P2PNetwork) Redo() (int, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	h := &network.history
+
+	if h.cursor == len(h.changes) {
+		return h.version(), entities.ErrNothingToRedo
+	}
+
+	c := h.changes[h.cursor]
+
+	positions := network.positions()
+	apply(positions, c.after, c.before)
+
+	network.reset(positions)
+
+	if c.queued {
+		network.queue = copyQueue(c.queueAfter)
+	}
+
+	h.cursor++
+
+	return h.version(), nil
+}
+
+// Version: returns the version of the current topology. Every operation which changes the topology or the queue adds one
+func (network *P2PNetwork) Version() int {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	return network.history.version()
+}
+
+// TraceVersion: returns the trace of the network at the given version
+func (network *P2PNetwork) TraceVersion(version int) ([]string, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	topology, sources, err := network.topologyAt(version)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := make([]string, 0, len(topology))
+
+	for _, t := range topology {
+		trace = append(trace, encode(t, sources))
+	}
+
+	return trace, nil
+}
+
+// RenderVersion: returns the trees of the network at the given version drawn one peer per line
+func (network *P2PNetwork) RenderVersion(version int) ([]string, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	topology, sources, err := network.topologyAt(version)
+	if err != nil {
+		return nil, err
+	}
+
+	drawings := make([]string, 0, len(topology))
+
+	for _, t := range topology {
+		drawings = append(drawings, render(t, sources))
+	}
+
+	return drawings, nil
+}
+
+// topologyAt: rebuilds the trees at the given version by walking the changes from the current version.
+// Returns the ids of the sources at the version too
+func (network *P2PNetwork) topologyAt(version int) ([]*tree.Tree, map[int]struct{}, error) {
+	h := &network.history
+
+	if version < h.offset || version > h.offset+len(h.changes) {
+		return nil, nil, entities.ErrVersionNotFound.Errorf("version %d is not in the history, versions %d to %d are kept", version, h.offset, h.offset+len(h.changes))
+	}
+
+	positions := network.positions()
+
+	for cursor := h.cursor; cursor > version-h.offset; cursor-- {
+		c := h.changes[cursor-1]
+		apply(positions, c.before, c.after)
+	}
+
+	for cursor := h.cursor; cursor < version-h.offset; cursor++ {
+		c := h.changes[cursor]
+		apply(positions, c.after, c.before)
+	}
+
+	topology, _, sources := build(positions)
+
+	return topology, sources, nil
+}
+
+// Diff: returns the differences between the topologies at the given versions
+func (network *P2PNetwork) Diff(from int, to int) (entities.Diff, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	before, _, err := network.topologyAt(from)
+	if err != nil {
+		return entities.Diff{}, err
+	}
+
+	after, _, err := network.topologyAt(to)
+	if err != nil {
+		return entities.Diff{}, err
+	}
+
+	return tree.Diff(before, after), nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestUndoRedo(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 10}).(*P2PNetwork)
+
+	_, err := network.Undo()
+	if !errors.Is(err, entities.ErrNothingToUndo) {
+		t.Errorf("expected %v, but got %v", entities.ErrNothingToUndo, err)
+	}
+
+	for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+		network.Join(node)
+	}
+
+	// a failed join does not change the topology, so it is not recorded
+	network.Join(n3)
+
+	if version := network.Version(); version != 5 {
+		t.Errorf("expected %d, but got %d", 5, version)
+	}
+
+	beforeLeave := []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"}
+	afterLeave := []string{"7(1/5)[ 3(2/3)[ 4(0/0) 6(0/0) ] ]"}
+
+	network.Leave(5)
+	assertTrace(t, network.Trace(), afterLeave)
+
+	version, err := network.Undo()
+	if err != nil || version != 5 {
+		t.Errorf("expected 5 <nil>, but got %d %v", version, err)
+	}
+
+	assertTrace(t, network.Trace(), beforeLeave)
+
+	// the treap is rebuilt, so joins go beneath the restored peers
+	if _, err = network.Redo(); err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	assertTrace(t, network.Trace(), afterLeave)
+
+	_, err = network.Redo()
+	if !errors.Is(err, entities.ErrNothingToRedo) {
+		t.Errorf("expected %v, but got %v", entities.ErrNothingToRedo, err)
+	}
+
+	// a new operation after an undo drops the operations which could be redone
+	network.Undo()
+	network.Join(n8)
+
+	assertTrace(t, network.Trace(), []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(1/5)[ 8(0/1) ] ] 6(0/0) ]"})
+
+	_, err = network.Redo()
+	if !errors.Is(err, entities.ErrNothingToRedo) {
+		t.Errorf("expected %v, but got %v", entities.ErrNothingToRedo, err)
+	}
+
+	// undo every operation back to the empty network
+	for i := 0; i < 6; i++ {
+		network.Undo()
+	}
+
+	assertTrace(t, network.Trace(), []string{})
+
+	network.Join(n1)
+	assertTrace(t, network.Trace(), []string{"1(0/1)"})
+}
+
+func TestUndoKeepsCrashes(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 10, DetectionDelay: time.Minute}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5} {
+		network.Join(node)
+	}
+
+	network.Fail(5)
+	network.Join(n6)
+	network.Undo()
+
+	// nobody joins beneath the crashed peer after the undo
+	network.Join(n7)
+
+	assertTrace(t, network.Trace(), []string{"3(3/3)[ 4(0/0) 5(0/1) 7(0/5) ]"})
+}
+
+func TestTraceVersion(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 3}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5, n7} {
+		network.Join(node)
+	}
+
+	network.Leave(5)
+	network.Undo()
+
+	testTable := []struct {
+		name          string
+		version       int
+		expected      []string
+		expectedError error
+	}{
+		{
+			name:          "dropped from the history",
+			version:       1,
+			expectedError: entities.ErrVersionNotFound,
+		},
+		{
+			name:     "oldest version kept",
+			version:  2,
+			expected: []string{"3(1/3)[ 4(0/0) ]"},
+		},
+		{
+			name:     "current version",
+			version:  4,
+			expected: []string{"3(3/3)[ 4(0/0) 5(0/1) 7(0/5) ]"},
+		},
+		{
+			name:     "undone version",
+			version:  5,
+			expected: []string{"3(2/3)[ 4(0/0) 7(0/5) ]"},
+		},
+		{
+			name:          "future version",
+			version:       6,
+			expectedError: entities.ErrVersionNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			trace, err := network.TraceVersion(testCase.version)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			if err == nil {
+				assertTrace(t, trace, testCase.expected)
+			}
+		})
+	}
+
+	// reading a version does not change the current topology
+	assertTrace(t, network.Trace(), []string{"3(3/3)[ 4(0/0) 5(0/1) 7(0/5) ]"})
+}
+
+func TestDiff(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 10}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+		network.Join(node)
+	}
+
+	network.Leave(5)
+
+	diff, err := network.Diff(5, 6)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(diff.Removed, []int{5}) {
+		t.Errorf("expected %v, but got %v", []int{5}, diff.Removed)
+	}
+
+	expected := []entities.ParentChange{{Id: 3, OldParent: 0, NewParent: 7}, {Id: 7, OldParent: 5, NewParent: 0}}
+
+	if !reflect.DeepEqual(diff.Parents, expected) {
+		t.Errorf("expected %v, but got %v", expected, diff.Parents)
+	}
+
+	_, err = network.Diff(0, 7)
+	if !errors.Is(err, entities.ErrVersionNotFound) {
+		t.Errorf("expected %v, but got %v", entities.ErrVersionNotFound, err)
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/tree"
+)
+
+// closer: compares the parents by how close they are to the given peer.
+// Parents in the same region are the closest, the others are compared by distance
+func closer(peer *tree.Peer) func(a *tree.Peer, b *tree.Peer) int {
+	return func(a *tree.Peer, b *tree.Peer) int {
+		sameA, sameB := peer.Location.SameRegion(a.Location), peer.Location.SameRegion(b.Location)
+
+		if sameA != sameB {
+			if sameA {
+				return -1
+			}
+
+			return 1
+		}
+
+		// parents in the same region are as close as each other
+		if sameA {
+			return 0
+		}
+
+		distanceA, distanceB := peer.Location.Distance(a.Location), peer.Location.Distance(b.Location)
+
+		switch {
+		case distanceA < distanceB:
+			return -1
+		case distanceA > distanceB:
+			return 1
+		}
+
+		return 0
+	}
+}
+
+// Locality: returns the number of edges between the regions and their length for each tree
+func (network *P2PNetwork) Locality() []entities.TreeLocality {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	report := make([]entities.TreeLocality, 0, len(network.topology))
+
+	for _, t := range network.topology {
+		locality := entities.TreeLocality{Root: t.GetRoot().Id}
+
+		for _, peer := range t.Peers() {
+			for _, child := range peer.Children {
+				locality.Edges++
+
+				if child.Location.SameRegion(peer.Location) {
+					continue
+				}
+
+				locality.CrossRegion++
+				locality.Distance += child.Location.Distance(peer.Location)
+			}
+		}
+
+		report = append(report, locality)
+	}
+
+	return report
+}
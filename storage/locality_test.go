@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+var (
+	// 1 in eu at x 0, 2 and 3 in us at x 100, 4 and 5 in sa at x 90
+	l1 = entities.Node{Id: 1, Capacity: 4, Location: entities.Location{Region: "eu", X: 0}}
+	l2 = entities.Node{Id: 2, Capacity: 2, Location: entities.Location{Region: "us", X: 100}}
+	l3 = entities.Node{Id: 3, Capacity: 0, Location: entities.Location{Region: "us", X: 100}}
+	l4 = entities.Node{Id: 4, Capacity: 0, Location: entities.Location{Region: "sa", X: 90}}
+	l5 = entities.Node{Id: 5, Capacity: 0, Location: entities.Location{Region: "sa", X: 90}}
+)
+
+func TestLocality(t *testing.T) {
+	testTable := []struct {
+		name     string
+		locality bool
+		expected []string
+		report   entities.TreeLocality
+	}{
+		{
+			name:     "most free capacity",
+			locality: false,
+			expected: []string{"1(3/4)[ 2(1/2)[ 5(0/0) ] 3(0/0) 4(0/0) ]"},
+			report:   entities.TreeLocality{Root: 1, Edges: 4, CrossRegion: 4, Distance: 100 + 100 + 90 + 10},
+		},
+		{
+			/*
+				3 joins 2 in its region, 4 joins 2 as the closest one, 5 joins 1 once 2 is full
+
+					1
+				   / \
+				  2   5
+				 / \
+				3   4
+			*/
+			name:     "same region then distance",
+			locality: true,
+			expected: []string{"1(2/4)[ 2(2/2)[ 3(0/0) 4(0/0) ] 5(0/0) ]"},
+			report:   entities.TreeLocality{Root: 1, Edges: 4, CrossRegion: 3, Distance: 100 + 10 + 90},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{Locality: testCase.locality, HistorySize: 10}).(*P2PNetwork)
+
+			for _, node := range []entities.Node{l1, l2, l3, l4, l5} {
+				network.Join(node)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+
+			report := network.Locality()
+
+			if len(report) != 1 || report[0] != testCase.report {
+				t.Errorf("expected %v, but got %v", testCase.report, report)
+			}
+
+			// the locations are kept by the history
+			network.Leave(1)
+			network.Undo()
+
+			if report := network.Locality(); len(report) != 1 || report[0] != testCase.report {
+				t.Errorf("expected %v, but got %v", testCase.report, report)
+			}
+		})
+	}
+}
+
+func TestLocalitySnapshot(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Locality: true}).(*P2PNetwork)
+
+	network.Join(l1)
+	network.Join(l2)
+
+	records := network.Snapshot()
+
+	restored := NewP2PNetworkWithOptions(Options{Locality: true}).(*P2PNetwork)
+
+	err := restored.Restore(records)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	// 3 joins 2 in its region, since the regions are restored
+	restored.Join(l3)
+
+	assertTrace(t, restored.Trace(), []string{"1(1/4)[ 2(1/2)[ 3(0/0) ] ]"})
+}
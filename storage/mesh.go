@@ -0,0 +1,621 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/domain/interfaces"
+	"p2p-network-simulator/logging"
+)
+
+// meshPeer: a peer of the mesh, which can have more than one parent
+type meshPeer struct {
+	Id          int
+	MaxCapacity int
+	Parents     []*meshPeer // sorted by id
+	Children    []*meshPeer // sorted by id
+}
+
+// free: returns the number of children the peer can still take
+func (peer *meshPeer) free() int {
+	return peer.MaxCapacity - len(peer.Children)
+}
+
+// MeshNetwork: a p2p network where each peer receives from up to K parents drawn from distinct subtrees.
+// Peers without a parent are the roots of the mesh
+type MeshNetwork struct {
+	// every peer of the mesh by id
+	peers map[int]*meshPeer
+
+	// keeps track of the last heartbeat of each peer
+	heartbeats map[int]time.Time
+
+	// running total of the links made again when a peer left or lost capacity
+	rehomed int
+
+	// running totals of the disruptions of the leaves
+	disruptions disruptions
+
+	// options the network was created with. Parents, TTL and Logger are used
+	options Options
+
+	// using mutex to prevent from the concurrent accesses to the network
+	lock sync.Mutex
+}
+
+// NewMeshNetwork: creates new mesh network with the given options. Zero parents means two parents
+func NewMeshNetwork(options Options) interfaces.P2PNetwork {
+	if options.Logger == nil {
+		options.Logger = logging.Discard()
+	}
+
+	if options.Parents <= 0 {
+		options.Parents = 2
+	}
+
+	return &MeshNetwork{
+		peers:      make(map[int]*meshPeer),
+		heartbeats: make(map[int]time.Time),
+		options:    options,
+	}
+}
+
+// Join: a new node joining the mesh beneath up to K parents. Without any parent it becomes a root
+func (network *MeshNetwork) Join(node entities.Node) (entities.Admission, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.peers[node.Id]; ok {
+		return entities.Admitted, entities.ErrDuplicateID.Errorf("id %d already reserved", node.Id)
+	}
+
+	peer := &meshPeer{Id: node.Id, MaxCapacity: node.Capacity}
+
+	for _, parent := range network.parentsFor(peer, network.options.Parents) {
+		link(parent, peer)
+	}
+
+	network.peers[node.Id] = peer
+	network.heartbeats[node.Id] = time.Now()
+
+	return entities.Admitted, nil
+}
+
+// Leave: a node leaving the mesh. Each child loses its link to the node and gets a replacement parent if any
+func (network *MeshNetwork) Leave(id int) (entities.LeaveReport, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	peer, ok := network.peers[id]
+	if !ok {
+		return entities.LeaveReport{}, entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	return network.leave(peer), nil
+}
+
+// leave: removes the peer from the mesh and reports the replacement of each lost link
+func (network *MeshNetwork) leave(peer *meshPeer) entities.LeaveReport {
+	depth := network.maxDepth()
+	rehomed := network.rehomed
+
+	for _, parent := range append([]*meshPeer(nil), peer.Parents...) {
+		unlink(parent, peer)
+	}
+
+	delete(network.peers, peer.Id)
+	delete(network.heartbeats, peer.Id)
+
+	children := append([]*meshPeer(nil), peer.Children...)
+	for _, child := range children {
+		unlink(peer, child)
+	}
+
+	reassignments := network.relink(peer, children)
+
+	// each child lost a single link, so each of them changed its parents
+	disruption := entities.Disruption{
+		Id:            peer.Id,
+		ChangedParent: len(reassignments),
+		Rehomed:       network.rehomed - rehomed,
+		DepthChange:   network.maxDepth() - depth,
+	}
+
+	network.disruptions.record(disruption)
+
+	return entities.LeaveReport{
+		Id:            peer.Id,
+		Reassignments: reassignments,
+		Dropped:       []int{},
+		Disruption:    disruption,
+	}
+}
+
+// maxDepth: the longest path from a root to a peer of the mesh
+func (network *MeshNetwork) maxDepth() int {
+	depth := 0
+
+	for _, d := range network.depths() {
+		if d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
+
+// relink: gives each child, which lost its link to the peer, a replacement parent if any.
+// The replacement is checked against the other parents of the child, not the ones of its descendants
+func (network *MeshNetwork) relink(peer *meshPeer, children []*meshPeer) []entities.Reassignment {
+	reassignments := make([]entities.Reassignment, 0, len(children))
+
+	for _, child := range children {
+		reassignment := entities.Reassignment{Id: child.Id, OldParent: peer.Id}
+
+		if parents := network.parentsFor(child, 1); len(parents) > 0 {
+			link(parents[0], child)
+			reassignment.NewParent = parents[0].Id
+			network.rehomed++
+
+			network.options.Logger.Debug("link rehomed", logging.F("node", child.Id), logging.F("parent", parents[0].Id), logging.F("lost", peer.Id))
+		}
+
+		reassignment.Root = len(child.Parents) == 0
+		reassignments = append(reassignments, reassignment)
+	}
+
+	return reassignments
+}
+
+/*
+parentsFor: picks up to k new parents for the peer, in the order of the most free capacity, the shallowest depth, then the lowest id.
+
+A candidate is skipped if it is the peer, a descendant of the peer, already a parent of it,
+an ancestor or a descendant of another parent, or shares a branch with another parent.
+The branches of a peer are its ancestors right beneath the roots, so the parents are drawn from distinct subtrees.
+*/
+func (network *MeshNetwork) parentsFor(peer *meshPeer, k int) []*meshPeer {
+	depths := network.depths()
+	descendants := closure(peer, func(p *meshPeer) []*meshPeer { return p.Children })
+
+	candidates := make([]*meshPeer, 0)
+
+	for _, candidate := range network.peers {
+		if candidate.free() <= 0 {
+			continue
+		}
+
+		if _, ok := descendants[candidate.Id]; ok {
+			continue
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].free() != candidates[j].free() {
+			return candidates[i].free() > candidates[j].free()
+		}
+
+		if depths[candidates[i].Id] != depths[candidates[j].Id] {
+			return depths[candidates[i].Id] < depths[candidates[j].Id]
+		}
+
+		return candidates[i].Id < candidates[j].Id
+	})
+
+	// the current parents of the peer limit the new ones, like the ones picked below
+	chosen := append([]*meshPeer(nil), peer.Parents...)
+	parents := make([]*meshPeer, 0, k)
+
+	for _, candidate := range candidates {
+		if len(parents) == k {
+			break
+		}
+
+		if compatible(candidate, chosen) {
+			chosen = append(chosen, candidate)
+			parents = append(parents, candidate)
+		}
+	}
+
+	return parents
+}
+
+// compatible: reports whether the candidate is not related to any of the chosen parents and does not share a branch with them
+func compatible(candidate *meshPeer, chosen []*meshPeer) bool {
+	ancestors := closure(candidate, func(p *meshPeer) []*meshPeer { return p.Parents })
+	heads := branches(candidate)
+
+	for _, parent := range chosen {
+		if _, ok := ancestors[parent.Id]; ok {
+			return false
+		}
+
+		if _, ok := closure(parent, func(p *meshPeer) []*meshPeer { return p.Parents })[candidate.Id]; ok {
+			return false
+		}
+
+		for id := range branches(parent) {
+			if _, ok := heads[id]; ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// closure: returns the ids of the peer and every peer reachable from it through the given links
+func closure(peer *meshPeer, next func(p *meshPeer) []*meshPeer) map[int]struct{} {
+	visited := map[int]struct{}{peer.Id: {}}
+	stack := []*meshPeer{peer}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, p := range next(current) {
+			if _, ok := visited[p.Id]; ok {
+				continue
+			}
+
+			visited[p.Id] = struct{}{}
+			stack = append(stack, p)
+		}
+	}
+
+	return visited
+}
+
+// branches: returns the ids of the peer's ancestors right beneath a root, including the peer itself.
+// A root is its own branch
+func branches(peer *meshPeer) map[int]struct{} {
+	if len(peer.Parents) == 0 {
+		return map[int]struct{}{peer.Id: {}}
+	}
+
+	heads := make(map[int]struct{})
+	stack := []*meshPeer{peer}
+	visited := map[int]struct{}{peer.Id: {}}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, parent := range current.Parents {
+			if len(parent.Parents) == 0 {
+				heads[current.Id] = struct{}{}
+				continue
+			}
+
+			if _, ok := visited[parent.Id]; !ok {
+				visited[parent.Id] = struct{}{}
+				stack = append(stack, parent)
+			}
+		}
+	}
+
+	return heads
+}
+
+// depths: returns the depth of each peer. Roots have zero depth, the others are one deeper than their deepest parent
+func (network *MeshNetwork) depths() map[int]int {
+	depths := make(map[int]int, len(network.peers))
+
+	var depth func(peer *meshPeer) int
+	depth = func(peer *meshPeer) int {
+		if d, ok := depths[peer.Id]; ok {
+			return d
+		}
+
+		d := 0
+		for _, parent := range peer.Parents {
+			if pd := depth(parent) + 1; pd > d {
+				d = pd
+			}
+		}
+
+		depths[peer.Id] = d
+		return d
+	}
+
+	for _, peer := range network.peers {
+		depth(peer)
+	}
+
+	return depths
+}
+
+// sorted: returns the peers sorted by depth then id
+func (network *MeshNetwork) sorted(depths map[int]int) []*meshPeer {
+	peers := make([]*meshPeer, 0, len(network.peers))
+	for _, peer := range network.peers {
+		peers = append(peers, peer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		if depths[peers[i].Id] != depths[peers[j].Id] {
+			return depths[peers[i].Id] < depths[peers[j].Id]
+		}
+
+		return peers[i].Id < peers[j].Id
+	})
+
+	return peers
+}
+
+// link: attaches the child beneath the parent, keeping both lists sorted by id
+func link(parent *meshPeer, child *meshPeer) {
+	parent.Children = insertPeer(parent.Children, child)
+	child.Parents = insertPeer(child.Parents, parent)
+}
+
+// unlink: detaches the child from the parent
+func unlink(parent *meshPeer, child *meshPeer) {
+	parent.Children = removePeer(parent.Children, child)
+	child.Parents = removePeer(child.Parents, parent)
+}
+
+// insertPeer: adds the peer to the peers, which are sorted by id
+func insertPeer(peers []*meshPeer, peer *meshPeer) []*meshPeer {
+	index := sort.Search(len(peers), func(i int) bool { return peers[i].Id >= peer.Id })
+
+	peers = append(peers, nil)
+	copy(peers[index+1:], peers[index:])
+	peers[index] = peer
+
+	return peers
+}
+
+// removePeer: removes the peer from the peers, keeping their order
+func removePeer(peers []*meshPeer, peer *meshPeer) []*meshPeer {
+	for i, p := range peers {
+		if p == peer {
+			return append(peers[:i], peers[i+1:]...)
+		}
+	}
+
+	return peers
+}
+
+/*
+Trace: returns one line per peer, sorted by depth then id, with the ids of its parents.
+Roots have no parents.
+
+	peer: id(#child/max capacity) <- parents
+
+	1(2/2)
+	2(1/2) <- 1
+	3(1/1) <- 1
+	4(0/1) <- 2 3
+*/
+func (network *MeshNetwork) Trace() []string {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	trace := make([]string, 0, len(network.peers))
+
+	for _, peer := range network.sorted(network.depths()) {
+		line := strconv.Itoa(peer.Id) + "(" + strconv.Itoa(len(peer.Children)) + "/" + strconv.Itoa(peer.MaxCapacity) + ")"
+
+		if len(peer.Parents) > 0 {
+			line += " <- " + joinIds(peer.Parents, " ")
+		}
+
+		trace = append(trace, line)
+	}
+
+	return trace
+}
+
+/*
+Render: draws the mesh one peer per line, sorted by depth then id.
+
+	peer: id (#child / max capacity) depth, parents
+
+	1 (2/2) depth 0
+	2 (1/2) depth 1 <- 1
+	3 (1/1) depth 1 <- 1
+	4 (0/1) depth 2 <- 2, 3
+*/
+func (network *MeshNetwork) Render() []string {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if len(network.peers) == 0 {
+		return []string{}
+	}
+
+	var builder strings.Builder
+
+	depths := network.depths()
+
+	for _, peer := range network.sorted(depths) {
+		builder.WriteString(strconv.Itoa(peer.Id) + " (" + strconv.Itoa(len(peer.Children)) + "/" + strconv.Itoa(peer.MaxCapacity) + ") depth " + strconv.Itoa(depths[peer.Id]))
+
+		if len(peer.Parents) > 0 {
+			builder.WriteString(" <- " + joinIds(peer.Parents, ", "))
+		}
+
+		builder.WriteString("\n")
+	}
+
+	return []string{builder.String()}
+}
+
+// joinIds: returns the ids of the peers separated by the given separator
+func joinIds(peers []*meshPeer, separator string) string {
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		ids = append(ids, strconv.Itoa(p.Id))
+	}
+
+	return strings.Join(ids, separator)
+}
+
+// Graph: returns the peers of the mesh as nodes, and every link as an edge
+func (network *MeshNetwork) Graph() entities.Graph {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	graph := entities.Graph{
+		Nodes: make([]entities.GraphNode, 0, len(network.peers)),
+		Edges: make([]entities.GraphEdge, 0, len(network.peers)),
+	}
+
+	depths := network.depths()
+
+	for _, peer := range network.peers {
+		graph.Nodes = append(graph.Nodes, entities.GraphNode{
+			Id:       peer.Id,
+			Capacity: peer.MaxCapacity,
+			Used:     len(peer.Children),
+			Depth:    depths[peer.Id],
+		})
+
+		for _, child := range peer.Children {
+			graph.Edges = append(graph.Edges, entities.GraphEdge{Parent: peer.Id, Child: child.Id})
+		}
+	}
+
+	sortGraph(graph)
+
+	return graph
+}
+
+// Stats: returns the summary of the mesh. Trees is the number of roots, and Rehomed the number of links made again
+func (network *MeshNetwork) Stats() entities.Stats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	stats := entities.Stats{
+		Peers:   len(network.peers),
+		Rehomed: network.rehomed,
+	}
+
+	depths := network.depths()
+
+	for _, peer := range network.peers {
+		if len(peer.Parents) == 0 {
+			stats.Trees++
+		}
+
+		stats.FreeCapacity += peer.free()
+
+		if depths[peer.Id] > stats.MaxDepth {
+			stats.MaxDepth = depths[peer.Id]
+		}
+	}
+
+	return stats
+}
+
+// SetCapacity: changes the max capacity of a node.
+// If the node has more children than the new capacity, then the children with the most parents lose their link
+// and get a replacement parent if any
+func (network *MeshNetwork) SetCapacity(id int, capacity int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if capacity < 0 {
+		return entities.ErrInvalidCapacity
+	}
+
+	peer, ok := network.peers[id]
+	if !ok {
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	peer.MaxCapacity = capacity
+
+	if len(peer.Children) <= capacity {
+		return nil
+	}
+
+	// keep the children which have the least other parents, since they lose the most
+	children := append([]*meshPeer(nil), peer.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return len(children[i].Parents) < len(children[j].Parents)
+	})
+
+	excess := children[capacity:]
+	for _, child := range excess {
+		unlink(peer, child)
+	}
+
+	network.relink(peer, excess)
+
+	return nil
+}
+
+// Heartbeat: a node tells the network it is still alive
+func (network *MeshNetwork) Heartbeat(id int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.peers[id]; !ok {
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	network.heartbeats[id] = time.Now()
+
+	return nil
+}
+
+// Evict: removes the nodes without a heartbeat within the TTL of the network, like they left
+func (network *MeshNetwork) Evict() []entities.LeaveReport {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	reports := make([]entities.LeaveReport, 0)
+
+	if network.options.TTL <= 0 {
+		return reports
+	}
+
+	now := time.Now()
+	expired := make([]int, 0)
+
+	for id, heartbeat := range network.heartbeats {
+		if now.Sub(heartbeat) > network.options.TTL {
+			expired = append(expired, id)
+		}
+	}
+
+	// evict in the order of ids, so the result does not depend on the map order
+	sort.Ints(expired)
+
+	for _, id := range expired {
+		reports = append(reports, network.leave(network.peers[id]))
+	}
+
+	return reports
+}
+
+// Disruption: returns the running totals of the disruptions of the leaves, with the records of the latest ones
+func (network *MeshNetwork) Disruption() entities.DisruptionStats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	return network.disruptions.stats()
+}
+
+// Merge: the roots of the mesh are kept apart, so nothing is merged
+func (network *MeshNetwork) Merge() int {
+	return 0
+}
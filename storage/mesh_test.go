@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/domain/interfaces"
+)
+
+func TestMeshJoin(t *testing.T) {
+	testTable := []struct {
+		name       string
+		parents    int
+		capacities []int
+		expected   []string
+	}{
+		{
+			name:       "first peer is a root",
+			parents:    2,
+			capacities: []int{2},
+			expected:   []string{"1(0/2)"},
+		},
+		{
+			/*
+				    1
+				   / \
+				  2   4
+				 / \ /
+				3   5
+			*/
+			name:       "parents from distinct subtrees",
+			parents:    2,
+			capacities: []int{2, 2, 1, 1, 1},
+			expected:   []string{"1(2/2)", "2(2/2) <- 1", "4(1/1) <- 1", "3(0/1) <- 2", "5(0/1) <- 2 4"},
+		},
+		{
+			name:       "single parent is a tree",
+			parents:    1,
+			capacities: []int{2, 2, 1, 1, 1},
+			expected:   []string{"1(2/2)", "2(2/2) <- 1", "4(0/1) <- 1", "3(0/1) <- 2", "5(0/1) <- 2"},
+		},
+		{
+			name:       "no free capacity starts a new root",
+			parents:    2,
+			capacities: []int{0, 1},
+			expected:   []string{"1(0/0)", "2(0/1)"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewMeshNetwork(Options{Parents: testCase.parents}).(*MeshNetwork)
+
+			join(network, testCase.capacities...)
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestMeshJoinDuplicate(t *testing.T) {
+	network := NewMeshNetwork(Options{}).(*MeshNetwork)
+
+	join(network, 1)
+
+	_, err := network.Join(entities.Node{Id: 1, Capacity: 1})
+	if !errors.Is(err, entities.ErrDuplicateID) {
+		t.Errorf("expected %v, but got %v", entities.ErrDuplicateID, err)
+	}
+}
+
+func TestMeshLeave(t *testing.T) {
+	testTable := []struct {
+		name          string
+		leave         int
+		expected      []string
+		reassignments []entities.Reassignment
+		expectedError error
+	}{
+		{
+			name:     "children get replacement parents",
+			leave:    2,
+			expected: []string{"1(2/2)", "3(1/1) <- 1", "4(1/1) <- 1", "5(0/1) <- 3 4"},
+			reassignments: []entities.Reassignment{
+				{Id: 3, OldParent: 2, NewParent: 1, Root: false},
+				{Id: 5, OldParent: 2, NewParent: 3, Root: false},
+			},
+		},
+		{
+			name:     "child without a replacement becomes a root",
+			leave:    1,
+			expected: []string{"2(2/2)", "3(1/1) <- 2", "4(1/1) <- 3", "5(0/1) <- 2 4"},
+			reassignments: []entities.Reassignment{
+				{Id: 2, OldParent: 1, NewParent: 0, Root: true},
+				{Id: 4, OldParent: 1, NewParent: 3, Root: false},
+			},
+		},
+		{
+			name:          "unknown node",
+			leave:         9,
+			expected:      []string{"1(2/2)", "2(2/2) <- 1", "4(1/1) <- 1", "3(0/1) <- 2", "5(0/1) <- 2 4"},
+			reassignments: nil,
+			expectedError: entities.ErrNodeNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+			join(network, 2, 2, 1, 1, 1)
+
+			report, err := network.Leave(testCase.leave)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+
+			if len(report.Reassignments) != len(testCase.reassignments) {
+				t.Fatalf("expected %v, but got %v", testCase.reassignments, report.Reassignments)
+			}
+
+			for i := range report.Reassignments {
+				if report.Reassignments[i] != testCase.reassignments[i] {
+					t.Errorf("expected %v, but got %v", testCase.reassignments[i], report.Reassignments[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMeshSetCapacity(t *testing.T) {
+	network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+	join(network, 2, 2, 1, 1, 1)
+
+	// 2 keeps 3, its only parent, and 5 moves to the free capacity of 3
+	err := network.SetCapacity(2, 1)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, network.Trace(), []string{"1(2/2)", "2(1/1) <- 1", "4(1/1) <- 1", "3(1/1) <- 2", "5(0/1) <- 3 4"})
+
+	if stats := network.Stats(); stats.Rehomed != 1 {
+		t.Errorf("expected %v, but got %v", 1, stats.Rehomed)
+	}
+}
+
+func TestMeshStats(t *testing.T) {
+	network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+	join(network, 2, 2, 1, 1, 1, 0)
+
+	expected := entities.Stats{Peers: 6, Trees: 1, MaxDepth: 3, FreeCapacity: 1}
+
+	if stats := network.Stats(); stats != expected {
+		t.Errorf("expected %v, but got %v", expected, stats)
+	}
+}
+
+func TestMeshGraph(t *testing.T) {
+	network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+	join(network, 2, 2, 1, 1, 1)
+
+	graph := network.Graph()
+
+	nodes := []entities.GraphNode{
+		{Id: 1, Capacity: 2, Used: 2, Depth: 0},
+		{Id: 2, Capacity: 2, Used: 2, Depth: 1},
+		{Id: 4, Capacity: 1, Used: 1, Depth: 1},
+		{Id: 3, Capacity: 1, Used: 0, Depth: 2},
+		{Id: 5, Capacity: 1, Used: 0, Depth: 2},
+	}
+
+	edges := []entities.GraphEdge{
+		{Parent: 1, Child: 2},
+		{Parent: 1, Child: 4},
+		{Parent: 2, Child: 3},
+		{Parent: 2, Child: 5},
+		{Parent: 4, Child: 5},
+	}
+
+	if len(graph.Nodes) != len(nodes) || len(graph.Edges) != len(edges) {
+		t.Fatalf("expected %v, but got %v", entities.Graph{Nodes: nodes, Edges: edges}, graph)
+	}
+
+	for i := range nodes {
+		if graph.Nodes[i] != nodes[i] {
+			t.Errorf("expected %v, but got %v", nodes[i], graph.Nodes[i])
+		}
+	}
+
+	for i := range edges {
+		if graph.Edges[i] != edges[i] {
+			t.Errorf("expected %v, but got %v", edges[i], graph.Edges[i])
+		}
+	}
+}
+
+func TestMeshRender(t *testing.T) {
+	network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+	join(network, 2, 2, 1, 1, 1)
+
+	expected := "1 (2/2) depth 0\n" +
+		"2 (2/2) depth 1 <- 1\n" +
+		"4 (1/1) depth 1 <- 1\n" +
+		"3 (0/1) depth 2 <- 2\n" +
+		"5 (0/1) depth 2 <- 2, 4\n"
+
+	assertTrace(t, network.Render(), []string{expected})
+}
+
+func TestCapabilities(t *testing.T) {
+	tree := NewP2PNetwork()
+	mesh := NewMeshNetwork(Options{})
+	stripes := NewStripedNetwork(Options{})
+
+	testTable := []struct {
+		name     string
+		has      func(network interfaces.P2PNetwork) bool
+		expected []bool // tree, mesh and stripes
+	}{
+		{
+			name:     "sources",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Sources); return ok },
+			expected: []bool{true, false, false},
+		},
+		{
+			name:     "failures",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Failures); return ok },
+			expected: []bool{true, false, false},
+		},
+		{
+			name:     "queue",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Queue); return ok },
+			expected: []bool{true, false, false},
+		},
+		{
+			name:     "stripes",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Stripes); return ok },
+			expected: []bool{false, false, true},
+		},
+		{
+			name:     "locality",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Locality); return ok },
+			expected: []bool{true, false, true},
+		},
+		{
+			name:     "reputation",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Reputation); return ok },
+			expected: []bool{true, false, true},
+		},
+		{
+			name:     "history",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.History); return ok },
+			expected: []bool{true, false, false},
+		},
+		{
+			name:     "transactions",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Transactions); return ok },
+			expected: []bool{true, false, false},
+		},
+		{
+			name:     "snapshots",
+			has:      func(network interfaces.P2PNetwork) bool { _, ok := network.(interfaces.Snapshots); return ok },
+			expected: []bool{true, false, false},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			for i, network := range []interfaces.P2PNetwork{tree, mesh, stripes} {
+				if result := testCase.has(network); result != testCase.expected[i] {
+					t.Errorf("expected %v, but got %v", testCase.expected[i], result)
+				}
+			}
+		})
+	}
+}
+
+func TestTreeGraph(t *testing.T) {
+	network := NewP2PNetwork()
+
+	network.Join(n3)
+	network.Join(n4)
+	network.Join(n5)
+
+	graph := network.Graph()
+
+	nodes := []entities.GraphNode{
+		{Id: 3, Capacity: 3, Used: 2, Depth: 0},
+		{Id: 4, Capacity: 0, Used: 0, Depth: 1},
+		{Id: 5, Capacity: 1, Used: 0, Depth: 1},
+	}
+
+	if len(graph.Nodes) != len(nodes) || len(graph.Edges) != 2 {
+		t.Fatalf("expected %v, but got %v", nodes, graph)
+	}
+
+	for i := range nodes {
+		if graph.Nodes[i] != nodes[i] {
+			t.Errorf("expected %v, but got %v", nodes[i], graph.Nodes[i])
+		}
+	}
+}
+
+func TestMeshDisruption(t *testing.T) {
+	network := NewMeshNetwork(Options{Parents: 2}).(*MeshNetwork)
+
+	join(network, 2, 2, 1, 1, 1)
+
+	// 3 and 5 lose their link to 2, and both get a replacement parent
+	report, err := network.Leave(2)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	expected := entities.Disruption{Id: 2, ChangedParent: 2, Rehomed: 2, DepthChange: 0}
+
+	if report.Disruption != expected {
+		t.Errorf("expected %+v, but got %+v", expected, report.Disruption)
+	}
+
+	if stats := network.Disruption(); stats.Leaves != 1 || len(stats.Records) != 1 || stats.Records[0] != expected {
+		t.Errorf("expected %+v, but got %+v", expected, stats)
+	}
+}
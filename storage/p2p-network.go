@@ -1,12 +1,13 @@
 package storage
 
 import (
-	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"p2p-network-simulator/domain/entities"
 	"p2p-network-simulator/domain/interfaces"
+	"p2p-network-simulator/logging"
 	"p2p-network-simulator/storage/treap"
 	"p2p-network-simulator/storage/tree"
 )
@@ -23,71 +24,218 @@ type P2PNetwork struct {
 	// keeps track of joint peer's id in a set data structure to ensure ids are unique
 	ids map[int]struct{}
 
+	// keeps track of the source peers, which are always the roots of their trees
+	sources map[int]struct{}
+
+	// peers waiting for free capacity, in the order they started waiting
+	queue []waiting
+
+	// keeps track of crashed peers which are not repaired yet, with the time they failed
+	failed map[int]time.Time
+
+	// keeps track of the last heartbeat of each peer
+	heartbeats map[int]time.Time
+
+	// keeps track of the session history of each peer id, also after the peer leaves
+	sessions map[int]session
+
+	// running totals of the restructuring done inside the network
+	reorders int
+	rehomed  int
+
+	// running totals of the disruptions of the leaves
+	disruptions disruptions
+
+	// reversible records of the last operations which changed the topology
+	history history
+
+	// options the network was created with
+	options Options
+
 	// using mutex to prevent from the concurrent accesses to the network
 	lock sync.Mutex
 }
 
-// NewP2PNetwork: creates new p2p network
+// Options: tunes the behaviour of the p2p network
+type Options struct {
+	// decides the parent among peers which have the same free capacity
+	TieBreak treap.TieBreak
+
+	// seeds any randomness, so identical sequences of operations produce identical traces
+	Seed int64
+
+	// merges the forest after each join and leave
+	AutoMerge bool
+
+	// moves a joining peer towards the root while it has more free capacity than its parent, like a leave reorders
+	Promote bool
+
+	// time taken to detect a crashed peer. the subtree of the peer is disconnected until then
+	DetectionDelay time.Duration
+
+	// peers without a heartbeat within this time are evicted. zero disables the eviction
+	TTL time.Duration
+
+	// structured logger for the restructuring done inside the network. nil discards the logs
+	Logger *logging.Logger
+
+	// max number of operations which can be undone. zero disables the history
+	HistorySize int
+
+	// max depth of a peer in its tree, roots have zero depth. zero means no limit
+	MaxDepth int
+
+	// places peers beneath parents in the same region, or the closest ones, instead of the ones with the most free capacity
+	Locality bool
+
+	// peers are stable once the mean length of their sessions, divided by one plus their failures, reaches this time.
+	// stable peers are preferred as parents and move above the newcomers. zero disables it
+	StableAfter time.Duration
+
+	// only sources can be roots. a peer is rejected when no peer has free capacity for it
+	SourcesOnly bool
+
+	// peers which cannot be placed wait for free capacity, instead of starting a new tree or being rejected
+	Queue QueuePolicy
+
+	// number of stripes the content is split into in the stripes topology. zero means two
+	Stripes int
+
+	// max number of parents of a peer in the mesh topology, drawn from distinct subtrees. zero means two
+	Parents int
+
+	// called after a crashed peer is removed in the background, once the crash is detected
+	OnRepair func(report entities.LeaveReport)
+}
+
+// NewP2PNetwork: creates new p2p network with the default options
 func NewP2PNetwork() interfaces.P2PNetwork {
+	return NewP2PNetworkWithOptions(Options{})
+}
+
+// NewP2PNetworkWithOptions: creates new p2p network with the given options
+func NewP2PNetworkWithOptions(options Options) interfaces.P2PNetwork {
+	if options.Logger == nil {
+		options.Logger = logging.Discard()
+	}
+
 	return &P2PNetwork{
-		topology: make([]*tree.Tree, 0),
-		treap:    treap.NewTreap(),
-		ids:      make(map[int]struct{}),
+		topology:   make([]*tree.Tree, 0),
+		treap:      treap.NewTreapWithTieBreak(options.TieBreak, options.Seed),
+		ids:        make(map[int]struct{}),
+		sources:    make(map[int]struct{}),
+		failed:     make(map[int]time.Time),
+		heartbeats: make(map[int]time.Time),
+		sessions:   make(map[int]session),
+		options:    options,
 	}
 }
 
-// Join: a new node joining the network
-func (network *P2PNetwork) Join(node entities.Node) error {
+// Join: a new node joining the network. Returns whether the node is placed or waits in the queue
+func (network *P2PNetwork) Join(node entities.Node) (entities.Admission, error) {
 	// using locks to prevent from concurrent access
 	network.lock.Lock()
 	defer network.lock.Unlock()
 
 	// check whether the given node id is already reserved
 	_, ok := network.ids[node.Id]
-	if ok {
-		return fmt.Errorf("id %d already reserved", node.Id)
+	if ok || network.queued(node.Id) {
+		return entities.Admitted, entities.ErrDuplicateID.Errorf("id %d already reserved", node.Id)
 	}
 
+	before := network.checkpoint()
+	defer network.commit(before)
+
 	// creating a new peer with given values
 	peer := tree.NewPeer(node)
 
-	// add to the network
-	network.add(peer)
+	// add to the network, unless there is no place for it
+	admission, err := network.admit(peer)
+	if err != nil || admission == entities.Queued {
+		return admission, err
+	}
 
 	// update the new node id
+	now := time.Now()
+
 	network.ids[node.Id] = struct{}{}
+	network.heartbeats[node.Id] = now
+	network.online(node.Id, now)
 
-	return nil
+	// the capacity of the new peer can take waiting peers
+	network.drain()
+
+	if network.options.AutoMerge {
+		network.merge()
+	}
+
+	return entities.Admitted, nil
 }
 
-// Leave: a node leaving the network
-func (network *P2PNetwork) Leave(id int) error {
+// Leave: a node leaving the network.
+// Returns the report of the peers which are attached to a different parent after the leave
+func (network *P2PNetwork) Leave(id int) (entities.LeaveReport, error) {
 	// using locks to prevent from concurrent access
 	network.lock.Lock()
 	defer network.lock.Unlock()
 
 	// locate the peer and the tree for the given id
-	var peer *tree.Peer
-	var tree *tree.Tree
+	peer, tree := network.locate(id)
+
+	// if the given id is neither in the topology nor in the queue, then return an error
+	if peer == nil && !network.queued(id) {
+		return entities.LeaveReport{}, entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	// a waiting peer leaves the queue
+	if peer == nil {
+		network.dequeue(id)
+		return entities.LeaveReport{Id: id, Reassignments: []entities.Reassignment{}, Dropped: []int{}}, nil
+	}
+
+	return network.leave(peer, tree), nil
+}
+
+// Stats: returns the summary of the network
+func (network *P2PNetwork) Stats() entities.Stats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	stats := entities.Stats{
+		Trees:    len(network.topology),
+		Reorders: network.reorders,
+		Rehomed:  network.rehomed,
+	}
 
 	for _, t := range network.topology {
-		peer = t.Locate(id)
-		tree = t
+		for _, peer := range t.Peers() {
+			stats.Peers++
+			stats.FreeCapacity += peer.Capacity
+		}
 
-		if peer != nil {
-			break
+		if depth := t.Height() - 1; depth > stats.MaxDepth {
+			stats.MaxDepth = depth
 		}
 	}
 
-	// if the given id is not in the topology, then return an error
-	if peer == nil {
-		return fmt.Errorf("cannot locate id %d node", id)
-	}
+	return stats
+}
 
-	// remove the peer from the network
-	network.remove(peer, tree)
+// Merge: attaches roots of the trees beneath peers which have free capacity in other trees.
+// Returns the number of trees merged into the others
+func (network *P2PNetwork) Merge() int {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
 
-	return nil
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	return network.merge()
 }
 
 // Trace: returns the current status of the network
@@ -99,16 +247,102 @@ func (network *P2PNetwork) Trace() []string {
 	var digram []string
 
 	for _, tree := range network.topology {
-		digram = append(digram, tree.Encode())
+		digram = append(digram, encode(tree, network.sources))
 	}
 
 	return digram
 }
 
+// Render: returns the trees of the network drawn one peer per line
+func (network *P2PNetwork) Render() []string {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	drawings := make([]string, 0, len(network.topology))
+
+	for _, tree := range network.topology {
+		drawings = append(drawings, render(tree, network.sources))
+	}
+
+	return drawings
+}
+
+// locate: returns the peer and the tree for the given id. if id is not in the topology, then returns nil
+func (network *P2PNetwork) locate(id int) (*tree.Peer, *tree.Tree) {
+	for _, t := range network.topology {
+		peer := t.Locate(id)
+
+		if peer != nil {
+			return peer, t
+		}
+	}
+
+	return nil, nil
+}
+
+// leave: removes the given peer from the network and reports the reassigned peers
+func (network *P2PNetwork) leave(peer *tree.Peer, tree *tree.Tree) entities.LeaveReport {
+	// a failed peer's subtree is not in the treap until it is repaired, unless it is beneath another crashed peer
+	if _, ok := network.failed[peer.Id]; ok {
+		delete(network.failed, peer.Id)
+
+		if !network.disconnected(peer) {
+			network.deepInsert(peer)
+		}
+	}
+
+	// keep track of the current parents, depth and re homed subtrees to find the disruption of the leave
+	parents := network.parents()
+	depth := network.maxDepth()
+	rehomed := network.rehomed
+
+	// remove the peer from the network
+	network.remove(peer, tree)
+	delete(network.heartbeats, peer.Id)
+	network.offline(peer.Id, time.Now())
+
+	// the freed capacity can take waiting peers
+	network.drain()
+
+	if network.options.AutoMerge {
+		network.merge()
+	}
+
+	reassignments := network.reassignments(parents, peer.Id)
+
+	disruption := entities.Disruption{
+		Id:            peer.Id,
+		ChangedParent: len(reassignments),
+		Rehomed:       network.rehomed - rehomed,
+		DepthChange:   network.maxDepth() - depth,
+	}
+
+	network.disruptions.record(disruption)
+
+	return entities.LeaveReport{
+		Id:            peer.Id,
+		Reassignments: reassignments,
+		Dropped:       network.dropped(parents, peer.Id),
+		Disruption:    disruption,
+	}
+}
+
 // add: adds the given peer to the network
 func (network *P2PNetwork) add(peer *tree.Peer) {
 	// get peer which has the most free capacity from the treap
-	parent := network.treap.Get()
+	parent := network.parentFor(peer)
+
+	// only sources can start a tree. the peer waits with its subtree, or leaves the network with it
+	if parent == nil && network.options.SourcesOnly {
+		if network.options.Queue != NoQueue {
+			network.enqueue(peer)
+			return
+		}
+
+		network.drop(peer)
+		return
+	}
 
 	// if there are no peers with free capacity, then add the given peer into a new tree
 	if parent == nil {
@@ -122,9 +356,7 @@ func (network *P2PNetwork) add(peer *tree.Peer) {
 		network.topology = append(network.topology, tree)
 
 		// if the given peer has free capacity, then insert it into the treap
-		if peer.Capacity > 0 {
-			network.treap.Insert(peer)
-		}
+		network.insert(peer)
 
 		return
 	}
@@ -135,14 +367,9 @@ func (network *P2PNetwork) add(peer *tree.Peer) {
 	// update the parent peer in the treap by delete and re insert it
 	network.treap.Delete(parent.Id)
 
-	// only insert peers into the treap, if they have free capacity
-	if parent.Capacity > 0 {
-		network.treap.Insert(parent)
-	}
-
-	if peer.Capacity > 0 {
-		network.treap.Insert(peer)
-	}
+	// only insert peers into the treap, if they have free capacity and they did not crash
+	network.insert(parent)
+	network.insert(peer)
 }
 
 // remove: removes the given peer from the network
@@ -156,8 +383,23 @@ func (network *P2PNetwork) remove(peer *tree.Peer, tree *tree.Tree) {
 
 	// delete the leaving peer from ids map and treap
 	delete(network.ids, peer.Id)
+	delete(network.sources, peer.Id)
 	network.treap.Delete(peer.Id)
 
+	// only sources can be roots, so the subtrees of a leaving root move to the trees of the other sources
+	if parent == nil && network.options.SourcesOnly && len(peer.Children) > 0 {
+		network.removeTree(tree)
+
+		// the subtrees are not attached beneath each other
+		for _, child := range peer.Children {
+			network.treap.DeepDelete(child)
+		}
+
+		network.rehome(peer, peer.Children)
+
+		return
+	}
+
 	// CASE A: removes a leaf peer
 	if len(peer.Children) == 0 {
 
@@ -169,9 +411,9 @@ func (network *P2PNetwork) remove(peer *tree.Peer, tree *tree.Tree) {
 
 		// CASE A-2: leaving peer is not the root of the tree
 
-		// update the parent peer in the treap
+		// update the parent peer in the treap, unless it crashed or it is beneath a crashed peer
 		network.treap.Delete(parent.Id)
-		network.treap.Insert(parent)
+		network.insert(parent)
 
 		// reorder the parent in the tree
 		network.reOrder(parent, tree)
@@ -211,6 +453,15 @@ func (network *P2PNetwork) remove(peer *tree.Peer, tree *tree.Tree) {
 		return peer.Children[i].Capacity > peer.Children[j].Capacity
 	})
 
+	// stable children which can take the others come first, so newcomers do not replace the leaving peer
+	if network.options.StableAfter > 0 {
+		now := time.Now()
+
+		sort.SliceStable(peer.Children, func(i, j int) bool {
+			return network.replaces(peer.Children[i], now) && !network.replaces(peer.Children[j], now)
+		})
+	}
+
 	// next child would be the child which has most capacity
 	nextChild := peer.Children[0]
 
@@ -227,17 +478,7 @@ func (network *P2PNetwork) remove(peer *tree.Peer, tree *tree.Tree) {
 	}
 
 	// remaining children would be added to the network
-	for _, child := range peer.Children[1:] {
-		// delete child's tree peers from the treap
-		// to prevent from adding the child to its own tree
-		network.treap.DeepDelete(child)
-
-		// add to the network
-		network.add(child)
-
-		// re insert the deleted child's tree peers
-		network.treap.DeepInsert(child)
-	}
+	network.rehome(peer, peer.Children[1:])
 
 	// reorder the next child in the tree
 	network.reOrder(nextChild, tree)
@@ -255,13 +496,32 @@ func (network *P2PNetwork) reOrder(peer *tree.Peer, tree *tree.Tree) {
 
 	// if the given peer has sufficient free capacity (parent peer free capacity +1),
 	// then reorder the peer with its parent (make the parent peer as child of the given peer)
-	if !(peer.Capacity > (peer.Parent.Capacity + 1)) {
+	if !network.outranks(peer) {
+		return
+	}
+
+	// crashed peers and their subtrees stay as they are until they are repaired
+	if !network.connected(peer) {
+		return
+	}
+
+	// the siblings of the peer move one level down, so they must stay within the max depth
+	if !network.reorderFits(peer) {
+		return
+	}
+
+	// sources stay the roots of their trees
+	if _, ok := network.sources[peer.Parent.Id]; ok {
 		return
 	}
 
 	parent := peer.Parent
 	grandParent := parent.Parent
 
+	network.reorders++
+
+	network.options.Logger.Debug("peer reordered", logging.F("node", peer.Id), logging.F("parent", parent.Id))
+
 	/*
 		  1(1/1)
 			|		reorder 10		         10(2/3)
@@ -303,6 +563,136 @@ func (network *P2PNetwork) reOrder(peer *tree.Peer, tree *tree.Tree) {
 	network.reOrder(peer, tree)
 }
 
+// merge: collapses the forest by attaching the roots of the trees beneath peers with free capacity.
+// Shallow trees are attached first, so the deep trees stay close to the roots
+func (network *P2PNetwork) merge() int {
+	merged := 0
+
+	for {
+		// sort the trees according to height
+		trees := make([]*tree.Tree, len(network.topology))
+		copy(trees, network.topology)
+
+		sort.SliceStable(trees, func(i, j int) bool {
+			return trees[i].Height() < trees[j].Height()
+		})
+
+		progress := false
+
+		for _, t := range trees {
+			if len(network.topology) == 1 {
+				return merged
+			}
+
+			root := t.GetRoot()
+
+			// sources are never attached beneath other peers
+			if _, ok := network.sources[root.Id]; ok {
+				continue
+			}
+
+			// delete tree peers from the treap to prevent from attaching the root to its own tree
+			network.treap.DeepDelete(root)
+
+			parent := network.parentFor(root)
+
+			// if there are no peers with free capacity in the other trees (within the max depth), then keep the tree as it is
+			if parent == nil {
+				network.deepInsert(root)
+				continue
+			}
+
+			host := network.locateTree(parent)
+
+			// attach the root beneath the parent and drop its tree from the topology
+			network.removeTree(t)
+			parent.AddChild(root)
+
+			// update the parent peer in the treap
+			network.treap.Delete(parent.Id)
+
+			if parent.Capacity > 0 {
+				network.treap.Insert(parent)
+			}
+
+			// re insert the deleted tree peers
+			network.deepInsert(root)
+
+			network.options.Logger.Debug("tree merged", logging.F("node", root.Id), logging.F("parent", parent.Id))
+
+			// reorder the attached root in the host tree
+			network.reOrder(root, host)
+
+			merged++
+			progress = true
+		}
+
+		if !progress {
+			return merged
+		}
+	}
+}
+
+// locateTree: returns the tree which the given peer belongs to
+func (network *P2PNetwork) locateTree(peer *tree.Peer) *tree.Tree {
+	root := peer.Root()
+
+	for _, t := range network.topology {
+		if t.GetRoot() == root {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// parents: returns the parent id of each peer in the network. Roots have zero as the parent id
+func (network *P2PNetwork) parents() map[int]int {
+	parents := make(map[int]int)
+
+	for _, t := range network.topology {
+		for _, peer := range t.Peers() {
+			parents[peer.Id] = 0
+
+			if peer.Parent != nil {
+				parents[peer.Id] = peer.Parent.Id
+			}
+		}
+	}
+
+	return parents
+}
+
+// reassignments: compares the given parents with the current parents,
+// and returns the peers attached to a different parent, sorted by id.
+// The peer for the given id is not reported, since it left the network
+func (network *P2PNetwork) reassignments(before map[int]int, left int) []entities.Reassignment {
+	after := network.parents()
+
+	reassignments := make([]entities.Reassignment, 0)
+
+	for id, oldParent := range before {
+		newParent, ok := after[id]
+
+		if id == left || !ok || oldParent == newParent {
+			continue
+		}
+
+		reassignments = append(reassignments, entities.Reassignment{
+			Id:        id,
+			OldParent: oldParent,
+			NewParent: newParent,
+			Root:      newParent == 0,
+		})
+	}
+
+	sort.Slice(reassignments, func(i, j int) bool {
+		return reassignments[i].Id < reassignments[j].Id
+	})
+
+	return reassignments
+}
+
 // removeTree: removes the given tree from the network topology
 func (network *P2PNetwork) removeTree(t *tree.Tree) {
 	topology := make([]*tree.Tree, 0)
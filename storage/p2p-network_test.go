@@ -3,11 +3,14 @@ package storage
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/domain/interfaces"
+	"p2p-network-simulator/storage/treap"
 )
 
-var network = NewP2PNetwork()
+var network = NewP2PNetwork().(*P2PNetwork)
 
 var (
 	n1  = entities.Node{Id: 1, Capacity: 1}
@@ -26,6 +29,13 @@ var (
 	n9 = entities.Node{Id: 9, Capacity: 1}
 )
 
+// join: joins nodes with the given capacities to the network, with ids from one
+func join(network interfaces.P2PNetwork, capacities ...int) {
+	for index, capacity := range capacities {
+		network.Join(entities.Node{Id: index + 1, Capacity: capacity})
+	}
+}
+
 func TestJoin(t *testing.T) {
 	testTable := []struct {
 		name          string
@@ -265,7 +275,7 @@ func TestJoin(t *testing.T) {
 
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			result := network.Join(testCase.node)
+			_, result := network.Join(testCase.node)
 
 			if result == nil && testCase.expectedError != nil {
 				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), result)
@@ -398,17 +408,17 @@ func TestLeave(t *testing.T) {
 			id:   7,
 			expected: []string{
 				"1(1/1)[ 12(0/0) ]",
-				"8(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(1/1)[ 9(0/1) ] ] ]",
+				"8(1/1)[ 9(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(0/1) ] ] ]",
 			},
 			expectedError: nil,
 			/*
 				1				   8
 				|			   	   |
-				12			   	   3
+				12			   	   9
+								   |
+								   3
 								 / | \
 								4  6  5
-								      |
-									  9
 			*/
 		},
 		{
@@ -416,41 +426,41 @@ func TestLeave(t *testing.T) {
 			id:   1,
 			expected: []string{
 				"12(0/0)",
-				"8(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(1/1)[ 9(0/1) ] ] ]",
+				"8(1/1)[ 9(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(0/1) ] ] ]",
 			},
 			expectedError: nil,
 			/*
 				12				   8
 							   	   |
-							   	   3
+							   	   9
+								   |
+								   3
 								 / | \
 								4  6  5
-								      |
-									  9
 			*/
 		},
 		{
 			name: "delete node 12",
 			id:   12,
 			expected: []string{
-				"8(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(1/1)[ 9(0/1) ] ] ]",
+				"8(1/1)[ 9(1/1)[ 3(3/3)[ 4(0/0) 6(0/0) 5(0/1) ] ] ]",
 			},
 			expectedError: nil,
 			/*
 					   8
 				   	   |
-				   	   3
+				   	   9
+					   |
+					   3
 					 / | \
 					4  6  5
-					      |
-						  9
 			*/
 		},
 	}
 
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			result := network.Leave(testCase.id)
+			_, result := network.Leave(testCase.id)
 
 			if result == nil && testCase.expectedError != nil {
 				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), result)
@@ -488,3 +498,315 @@ func TestLeave(t *testing.T) {
 		})
 	}
 }
+
+func TestNewP2PNetworkWithOptions(t *testing.T) {
+	testTable := []struct {
+		name    string
+		options Options
+		parent  int // parent of 2 on the tie between 9 and 5
+	}{
+		{
+			name:    "shallowest depth",
+			options: Options{TieBreak: treap.ShallowestDepth},
+			parent:  9,
+		},
+		{
+			name:    "lowest id",
+			options: Options{TieBreak: treap.LowestId},
+			parent:  5,
+		},
+		{
+			name:    "seeded random",
+			options: Options{TieBreak: treap.Random, Seed: 7},
+			parent:  5,
+		},
+	}
+
+	nodes := []entities.Node{n3, n4, n5, n6, n7, n8, n9, n1, n2, n10, n11, n12, n13}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			first := NewP2PNetworkWithOptions(testCase.options).(*P2PNetwork)
+			second := NewP2PNetworkWithOptions(testCase.options).(*P2PNetwork)
+
+			// identical sequences of operations must produce identical traces
+			for _, network := range []interfaces.P2PNetwork{first, second} {
+				for _, node := range nodes {
+					network.Join(node)
+				}
+
+				network.Leave(7)
+				network.Leave(3)
+			}
+
+			a, b := first.Trace(), second.Trace()
+
+			if len(a) != len(b) {
+				t.Fatalf("expected %v, but got %v", a, b)
+			}
+
+			for i := range a {
+				if a[i] != b[i] {
+					t.Errorf("expected %s, but got %s", a[i], b[i])
+				}
+			}
+
+			/*
+				9 at depth 0 and 5 at depth 1 have the same free capacity when 2 joins
+
+					9(1/2)
+					  |
+					5(0/1)
+			*/
+			network := NewP2PNetworkWithOptions(testCase.options).(*P2PNetwork)
+
+			network.Join(entities.Node{Id: 9, Capacity: 2})
+			network.Join(entities.Node{Id: 5, Capacity: 1})
+			network.Join(entities.Node{Id: 2, Capacity: 0})
+
+			peer, _ := network.locate(2)
+
+			if peer.Parent == nil || peer.Parent.Id != testCase.parent {
+				t.Errorf("expected %d, but got %v", testCase.parent, peer.Parent)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	testTable := []struct {
+		name           string
+		nodes          []entities.Node
+		expectedMerged int
+		expected       []string
+	}{
+		{
+			/*
+				12  13  3   ---->       3
+									   / \
+									  12  13
+			*/
+			name:           "attach roots without capacity",
+			nodes:          []entities.Node{n12, n13, n3},
+			expectedMerged: 2,
+			expected:       []string{"3(2/3)[ 12(0/0) 13(0/0) ]"},
+		},
+		{
+			/*
+				1   13  11  ---->      11
+				|					  /  \
+				12					 13   1
+										  |
+										  12
+			*/
+			name:           "shallow trees first",
+			nodes:          []entities.Node{n1, n12, n13, n11},
+			expectedMerged: 2,
+			expected:       []string{"11(2/2)[ 13(0/0) 1(1/1)[ 12(0/0) ] ]"},
+		},
+		{
+			name:           "no free capacity",
+			nodes:          []entities.Node{n12, n13},
+			expectedMerged: 0,
+			expected:       []string{"12(0/0)", "13(0/0)"},
+		},
+		{
+			name:           "empty network",
+			nodes:          nil,
+			expectedMerged: 0,
+			expected:       nil,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetwork().(*P2PNetwork)
+
+			for _, node := range testCase.nodes {
+				network.Join(node)
+			}
+
+			merged := network.Merge()
+
+			if merged != testCase.expectedMerged {
+				t.Errorf("expected %d, but got %d", testCase.expectedMerged, merged)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestAutoMerge(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{AutoMerge: true}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n12, n13, n3} {
+		network.Join(node)
+	}
+
+	expected := "3(2/3)[ 12(0/0) 13(0/0) ]"
+
+	got := network.Trace()
+	if len(got) != 1 || got[0] != expected {
+		t.Errorf("expected [%s], but got %v", expected, got)
+	}
+}
+
+func TestLeaveReport(t *testing.T) {
+	network := NewP2PNetwork().(*P2PNetwork)
+
+	/*
+			3
+		  / | \
+		 4  5  6
+			|
+			7
+	*/
+	for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+		network.Join(node)
+	}
+
+	testTable := []struct {
+		name          string
+		id            int
+		expected      []entities.Reassignment
+		expectedError error
+	}{
+		{
+			/*
+					7
+					|
+					3
+				   / \
+				  4   6
+			*/
+			name: "delete node 5",
+			id:   5,
+			expected: []entities.Reassignment{
+				{Id: 3, OldParent: 0, NewParent: 7, Root: false},
+				{Id: 7, OldParent: 5, NewParent: 0, Root: true},
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "delete leaf node 4",
+			id:            4,
+			expected:      []entities.Reassignment{},
+			expectedError: nil,
+		},
+		{
+			name:          "delete not exists node",
+			id:            5,
+			expected:      nil,
+			expectedError: errors.New("cannot locate id 5 node"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			report, err := network.Leave(testCase.id)
+
+			if err == nil && testCase.expectedError != nil {
+				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), err)
+			}
+
+			if err != nil && testCase.expectedError == nil {
+				t.Errorf("expected %v, but got %s", testCase.expectedError, err.Error())
+			}
+
+			if len(report.Reassignments) != len(testCase.expected) {
+				t.Fatalf("expected %v, but got %v", testCase.expected, report.Reassignments)
+			}
+
+			for i, reassignment := range report.Reassignments {
+				if reassignment != testCase.expected[i] {
+					t.Errorf("expected %v, but got %v", testCase.expected[i], reassignment)
+				}
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	network := NewP2PNetwork().(*P2PNetwork)
+
+	/*
+			3
+		  / | \
+		 4  5  6		12
+			|
+			7
+	*/
+	for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+		network.Join(node)
+	}
+
+	network.Restore(append(network.Snapshot(), entities.PeerRecord{Id: 12}))
+
+	expected := entities.Stats{Peers: 6, Trees: 2, MaxDepth: 2, FreeCapacity: 5}
+
+	if stats := network.Stats(); stats != expected {
+		t.Errorf("expected %+v, but got %+v", expected, stats)
+	}
+
+	/*
+			7
+			|
+			3
+		   / \
+		  4   6		12
+	*/
+	network.Leave(5)
+
+	expected = entities.Stats{Peers: 5, Trees: 2, MaxDepth: 2, FreeCapacity: 5, Reorders: 1}
+
+	if stats := network.Stats(); stats != expected {
+		t.Errorf("expected %+v, but got %+v", expected, stats)
+	}
+}
+
+func TestDomainErrors(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{DetectionDelay: time.Minute}).(*P2PNetwork)
+	network.Join(n1)
+	network.Join(n2)
+	network.Fail(2)
+
+	_, joinErr := network.Join(n1)
+	_, leaveErr := network.Leave(9)
+	_, failErr := network.Fail(2)
+
+	testTable := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "duplicate id",
+			err:      joinErr,
+			expected: entities.ErrDuplicateID,
+		},
+		{
+			name:     "node not found",
+			err:      leaveErr,
+			expected: entities.ErrNodeNotFound,
+		},
+		{
+			name:     "already failed",
+			err:      failErr,
+			expected: entities.ErrAlreadyFailed,
+		},
+		{
+			name:     "invalid topology",
+			err:      network.Restore([]entities.PeerRecord{{Id: 3, Capacity: 1, Parent: 4}}),
+			expected: entities.ErrInvalidTopology,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			if !errors.Is(testCase.err, testCase.expected) {
+				t.Errorf("expected %v, but got %v", testCase.expected, testCase.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import "testing"
+
+func TestPromote(t *testing.T) {
+	testTable := []struct {
+		name       string
+		capacities []int
+		promote    bool
+		expected   []string
+		maxDepth   int
+	}{
+		{
+			name:       "chain without promotion",
+			capacities: []int{1, 1, 1, 1, 4, 4},
+			promote:    false,
+			expected:   []string{"1(1/1)[ 2(1/1)[ 3(1/1)[ 4(1/1)[ 5(1/4)[ 6(0/4) ] ] ] ] ]"},
+			maxDepth:   5,
+		},
+		{
+			/*
+				high capacity joiners move above the low capacity peers
+
+						6
+					  /   \\
+					 5     1
+				   / | \\
+				  4  3  2
+			*/
+			name:       "chain with promotion",
+			capacities: []int{1, 1, 1, 1, 4, 4},
+			promote:    true,
+			expected:   []string{"6(2/4)[ 5(3/4)[ 4(0/1) 3(0/1) 2(0/1) ] 1(0/1) ]"},
+			maxDepth:   2,
+		},
+		{
+			name:       "descending capacities are not promoted",
+			capacities: []int{3, 2, 1},
+			promote:    true,
+			expected:   []string{"1(2/3)[ 2(0/2) 3(0/1) ]"},
+			maxDepth:   1,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{Promote: testCase.promote}).(*P2PNetwork)
+
+			join(network, testCase.capacities...)
+
+			assertTrace(t, network.Trace(), testCase.expected)
+
+			if stats := network.Stats(); stats.MaxDepth != testCase.maxDepth {
+				t.Errorf("expected %v, but got %v", testCase.maxDepth, stats.MaxDepth)
+			}
+		})
+	}
+}
+
+func TestPromoteSkewed(t *testing.T) {
+	// few high capacity peers join after many low capacity peers
+	capacities := make([]int, 0)
+
+	for i := 0; i < 30; i++ {
+		capacities = append(capacities, 1)
+	}
+
+	capacities = append(capacities, 10, 10, 10)
+
+	plain := NewP2PNetworkWithOptions(Options{}).(*P2PNetwork)
+	join(plain, capacities...)
+
+	promoted := NewP2PNetworkWithOptions(Options{Promote: true}).(*P2PNetwork)
+	join(promoted, capacities...)
+
+	before, after := plain.Stats().MaxDepth, promoted.Stats().MaxDepth
+
+	// 32 levels without promotion, 8 with it
+	if after*4 > before {
+		t.Errorf("expected max depth at most %v, but got %v", before/4, after)
+	}
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+// QueuePolicy: decides whether the peers which cannot be placed wait for free capacity, and in which order
+type QueuePolicy int
+
+const (
+	// NoQueue: peers which cannot be placed start a new tree, or are rejected
+	NoQueue QueuePolicy = iota
+
+	// FIFO: peers are admitted in the order they started waiting
+	FIFO
+
+	// Priority: peers with the most capacity are admitted first, since they bring the most free capacity.
+	// Peers with the same capacity are admitted in the order they started waiting
+	Priority
+)
+
+// String: returns the name of the queue policy
+func (qp QueuePolicy) String() string {
+	switch qp {
+	case NoQueue:
+		return "none"
+	case FIFO:
+		return "fifo"
+	case Priority:
+		return "priority"
+	}
+
+	return "unknown"
+}
+
+// ParseQueuePolicy: returns the queue policy for the given name
+func ParseQueuePolicy(name string) (QueuePolicy, bool) {
+	for _, qp := range []QueuePolicy{NoQueue, FIFO, Priority} {
+		if qp.String() == name {
+			return qp, true
+		}
+	}
+
+	return NoQueue, false
+}
+
+// waiting: a peer waiting for free capacity, with the subtree which waits with it
+type waiting struct {
+	peer  *tree.Peer
+	since time.Time
+}
+
+// copyQueue: returns a copy of the waiting peers and their subtrees, which the network does not share
+func copyQueue(queue []waiting) []waiting {
+	copied := make([]waiting, 0, len(queue))
+
+	for _, w := range queue {
+		copied = append(copied, waiting{peer: copySubtree(w.peer), since: w.since})
+	}
+
+	return copied
+}
+
+// sameQueue: reports whether the given queues have the same waiting subtrees in the same order
+func sameQueue(a []waiting, b []waiting) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if tree.NewTree(a[i].peer).Encode() != tree.NewTree(b[i].peer).Encode() || !a[i].since.Equal(b[i].since) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Queue: returns the peers waiting for free capacity, in the order they would be admitted
+func (network *P2PNetwork) Queue() []entities.QueuedPeer {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	queue := make([]entities.QueuedPeer, 0, len(network.queue))
+
+	for _, w := range network.ordered() {
+		queue = append(queue, entities.QueuedPeer{
+			Id:       w.peer.Id,
+			Capacity: w.peer.MaxCapacity,
+			Peers:    len(tree.NewTree(w.peer).Peers()),
+			Since:    w.since,
+		})
+	}
+
+	return queue
+}
+
+// enqueue: the given peer and its subtree wait for free capacity. The peers are not in the treap
+func (network *P2PNetwork) enqueue(peer *tree.Peer) {
+	for _, p := range tree.NewTree(peer).Peers() {
+		delete(network.ids, p.Id)
+		delete(network.sources, p.Id)
+		delete(network.failed, p.Id)
+		delete(network.heartbeats, p.Id)
+		network.offline(p.Id, time.Now())
+	}
+
+	peer.SetParent(nil)
+
+	network.queue = append(network.queue, waiting{peer: peer, since: time.Now()})
+
+	network.options.Logger.Debug("peer queued", logging.F("node", peer.Id))
+}
+
+// ordered: returns the waiting peers in the order of the queue policy
+func (network *P2PNetwork) ordered() []waiting {
+	ordered := make([]waiting, len(network.queue))
+	copy(ordered, network.queue)
+
+	if network.options.Queue == Priority {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].peer.MaxCapacity > ordered[j].peer.MaxCapacity
+		})
+	}
+
+	return ordered
+}
+
+// drain: admits the waiting peers which can be placed now, in the order of the queue policy.
+// A peer which still cannot be placed does not block the peers behind it
+func (network *P2PNetwork) drain() {
+	if len(network.queue) == 0 {
+		return
+	}
+
+	remaining := make([]waiting, 0)
+
+	for _, w := range network.ordered() {
+		if network.place(w.peer) != nil {
+			remaining = append(remaining, w)
+			continue
+		}
+
+		now := time.Now()
+
+		for _, p := range tree.NewTree(w.peer).Peers() {
+			network.ids[p.Id] = struct{}{}
+			network.heartbeats[p.Id] = now
+			network.online(p.Id, now)
+		}
+
+		// the peers of the subtree are only inserted with their root
+		network.treap.DeepInsert(w.peer)
+
+		network.options.Logger.Debug("peer admitted", logging.F("node", w.peer.Id), logging.F("waited_ms", now.Sub(w.since).Milliseconds()))
+	}
+
+	// keep the order the peers started waiting
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return remaining[i].since.Before(remaining[j].since)
+	})
+
+	network.queue = remaining
+}
+
+// queued: reports whether the peer for the given id is waiting in the queue
+func (network *P2PNetwork) queued(id int) bool {
+	for _, w := range network.queue {
+		if tree.NewTree(w.peer).Locate(id) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dequeue: the peer for the given id stops waiting. The peers of its subtree keep waiting on their own
+func (network *P2PNetwork) dequeue(id int) bool {
+	for index, w := range network.queue {
+		peer := tree.NewTree(w.peer).Locate(id)
+		if peer == nil {
+			continue
+		}
+
+		queue := make([]waiting, 0, len(network.queue)+len(peer.Children))
+		queue = append(queue, network.queue[:index]...)
+
+		if peer == w.peer {
+			queue = append(queue, network.queue[index+1:]...)
+		} else {
+			peer.Parent.RemoveChild(peer)
+			queue = append(queue, network.queue[index:]...)
+		}
+
+		children := make([]*tree.Peer, len(peer.Children))
+		copy(children, peer.Children)
+
+		for _, child := range children {
+			peer.RemoveChild(child)
+			queue = append(queue, waiting{peer: child, since: w.since})
+		}
+
+		network.queue = queue
+
+		return true
+	}
+
+	return false
+}
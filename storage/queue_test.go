@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestParseQueuePolicy(t *testing.T) {
+	testTable := []struct {
+		name     string
+		input    string
+		expected QueuePolicy
+		ok       bool
+	}{
+		{
+			name:     "none",
+			input:    "none",
+			expected: NoQueue,
+			ok:       true,
+		},
+		{
+			name:     "fifo",
+			input:    "fifo",
+			expected: FIFO,
+			ok:       true,
+		},
+		{
+			name:     "priority",
+			input:    "priority",
+			expected: Priority,
+			ok:       true,
+		},
+		{
+			name:     "unknown",
+			input:    "lifo",
+			expected: NoQueue,
+			ok:       false,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, ok := ParseQueuePolicy(testCase.input)
+
+			if result != testCase.expected {
+				t.Errorf("expected %s, but got %s", testCase.expected, result)
+			}
+
+			if ok != testCase.ok {
+				t.Errorf("expected %v, but got %v", testCase.ok, ok)
+			}
+		})
+	}
+}
+
+// queueIds: returns the ids of the waiting peers in the order they would be admitted
+func queueIds(network *P2PNetwork) []int {
+	ids := make([]int, 0)
+
+	for _, q := range network.Queue() {
+		ids = append(ids, q.Id)
+	}
+
+	return ids
+}
+
+func assertIds(t *testing.T, got []int, expected []int) {
+	t.Helper()
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v, but got %v", expected, got)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	testTable := []struct {
+		name          string
+		policy        QueuePolicy
+		expected      []string
+		expectedQueue []int
+	}{
+		{
+			name:          "fifo admits the first waiting peer",
+			policy:        FIFO,
+			expected:      []string{"1(1/1)[ 3(0/0) ]"},
+			expectedQueue: []int{4},
+		},
+		{
+			name:          "priority admits the peer with the most capacity",
+			policy:        Priority,
+			expected:      []string{"1(1/1)[ 4(1/2)[ 3(0/0) ] ]"},
+			expectedQueue: []int{},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{Queue: testCase.policy}).(*P2PNetwork)
+
+			network.Join(entities.Node{Id: 1, Capacity: 1})
+			network.Join(entities.Node{Id: 2, Capacity: 0})
+
+			// the network is full, 3 and 4 wait
+			for _, node := range []entities.Node{{Id: 3, Capacity: 0}, {Id: 4, Capacity: 2}} {
+				if admission, err := network.Join(node); err != nil || admission != entities.Queued {
+					t.Fatalf("expected %v, but got %v, %v", entities.Queued, admission, err)
+				}
+			}
+
+			assertTrace(t, network.Trace(), []string{"1(1/1)[ 2(0/0) ]"})
+
+			// the leave frees a place
+			if _, err := network.Leave(2); err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+			assertIds(t, queueIds(network), testCase.expectedQueue)
+		})
+	}
+}
+
+func TestQueueOperations(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 0})
+	network.Join(entities.Node{Id: 2, Capacity: 0})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+
+	assertIds(t, queueIds(network), []int{2, 3})
+
+	// the id of a waiting peer is reserved
+	if _, err := network.Join(entities.Node{Id: 2, Capacity: 1}); !errors.Is(err, entities.ErrDuplicateID) {
+		t.Errorf("expected %v, but got %v", entities.ErrDuplicateID, err)
+	}
+
+	// a waiting peer can leave the queue
+	if _, err := network.Leave(2); err != nil {
+		t.Errorf("expected %v, but got %v", nil, err)
+	}
+
+	assertIds(t, queueIds(network), []int{3})
+
+	// more capacity admits the waiting peer
+	if err := network.SetCapacity(1, 1); err != nil {
+		t.Errorf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 3(0/0) ]"})
+	assertIds(t, queueIds(network), []int{})
+}
+
+func TestQueueSources(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO, SourcesOnly: true}).(*P2PNetwork)
+
+	network.AddSource(entities.Node{Id: 1, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 1})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+
+	// the subtree of 2 waits for another source, instead of leaving the network
+	report, err := network.Leave(1)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertIds(t, report.Dropped, []int{})
+
+	queue := network.Queue()
+	if len(queue) != 1 || queue[0].Id != 2 || queue[0].Peers != 2 {
+		t.Fatalf("expected %v, but got %v", "2 waiting with 3", queue)
+	}
+
+	network.AddSource(entities.Node{Id: 5, Capacity: 1})
+
+	assertTrace(t, network.Trace(), []string{"source 5: 5(1/1)[ 2(1/1)[ 3(0/0) ] ]"})
+	assertIds(t, queueIds(network), []int{})
+}
+
+func TestQueueUndo(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO, HistorySize: 10}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 0})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+
+	// the leave admits the waiting peer
+	network.Leave(2)
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 3(0/0) ]"})
+	assertIds(t, queueIds(network), []int{})
+
+	// undo brings the admitted peer back to the queue
+	if _, err := network.Undo(); err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 2(0/0) ]"})
+	assertIds(t, queueIds(network), []int{3})
+
+	if _, err := network.Redo(); err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 3(0/0) ]"})
+	assertIds(t, queueIds(network), []int{})
+
+	// the join which queued the peer is undone too
+	network.Undo()
+	network.Undo()
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 2(0/0) ]"})
+	assertIds(t, queueIds(network), []int{})
+}
+
+func TestQueueLeaveUndo(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO, HistorySize: 10}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 0})
+	network.Join(entities.Node{Id: 2, Capacity: 0})
+
+	// the waiting peer leaves the queue, which is a version too
+	network.Leave(2)
+
+	if version := network.Version(); version != 3 {
+		t.Errorf("expected %v, but got %v", 3, version)
+	}
+
+	network.Undo()
+	assertIds(t, queueIds(network), []int{2})
+
+	network.Redo()
+	assertIds(t, queueIds(network), []int{})
+
+	// the id is free again
+	if _, err := network.Join(entities.Node{Id: 2, Capacity: 0}); err != nil {
+		t.Errorf("expected %v, but got %v", nil, err)
+	}
+}
+
+func TestQueueSubtreeLeave(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO, SourcesOnly: true, HistorySize: 10}).(*P2PNetwork)
+
+	network.AddSource(entities.Node{Id: 1, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 1})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+
+	// 2 waits with 3 beneath it
+	network.Leave(1)
+
+	version := network.Version()
+
+	// the leaf of the waiting subtree leaves, and the root of the subtree keeps waiting
+	network.Leave(3)
+
+	if result := network.Version(); result != version+1 {
+		t.Errorf("expected %v, but got %v", version+1, result)
+	}
+
+	network.Undo()
+
+	queue := network.Queue()
+	if len(queue) != 1 || queue[0].Peers != 2 {
+		t.Errorf("expected %v, but got %v", "2 waiting with 3", queue)
+	}
+}
+
+func TestQueueRehome(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Queue: FIFO}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 2})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+	network.Join(entities.Node{Id: 4, Capacity: 0})
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 2(2/2)[ 3(0/0) 4(0/0) ] ]"})
+
+	// 3 takes the place of 2 beneath 1, and 4 has no place, so it waits instead of starting a tree
+	report, err := network.Leave(2)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, network.Trace(), []string{"1(1/1)[ 3(0/0) ]"})
+	assertIds(t, queueIds(network), []int{4})
+	assertIds(t, report.Dropped, []int{})
+
+	// more capacity admits the subtree
+	network.SetCapacity(1, 2)
+
+	assertTrace(t, network.Trace(), []string{"1(2/2)[ 3(0/0) 4(0/0) ]"})
+}
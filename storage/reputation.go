@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/tree"
+)
+
+// session: the session history of a peer id
+type session struct {
+	joins    int
+	failures int
+
+	// total length of the ended sessions
+	ended time.Duration
+
+	// start of the current session, zero while the peer is not in the network
+	since time.Time
+}
+
+// average: the mean length of the sessions, the current one included
+func (s session) average(now time.Time) time.Duration {
+	if s.joins == 0 {
+		return 0
+	}
+
+	total := s.ended
+	if !s.since.IsZero() {
+		total += now.Sub(s.since)
+	}
+
+	return total / time.Duration(s.joins)
+}
+
+// online: starts a session of the peer for the given id
+func (network *P2PNetwork) online(id int, now time.Time) {
+	s := network.sessions[id]
+
+	s.joins++
+	s.since = now
+
+	network.sessions[id] = s
+}
+
+// offline: ends the current session of the peer for the given id
+func (network *P2PNetwork) offline(id int, now time.Time) {
+	s, ok := network.sessions[id]
+	if !ok || s.since.IsZero() {
+		return
+	}
+
+	s.ended += now.Sub(s.since)
+	s.since = time.Time{}
+
+	network.sessions[id] = s
+}
+
+// stable: reports whether the mean session of the peer for the given id, divided by one plus its failures,
+// reaches the stable after option. Always false, if the option is disabled
+func (network *P2PNetwork) stable(id int, now time.Time) bool {
+	if network.options.StableAfter <= 0 {
+		return false
+	}
+
+	s := network.sessions[id]
+
+	return s.average(now)/time.Duration(1+s.failures) >= network.options.StableAfter
+}
+
+// steadier: compares the parents by their stability. Stable parents come before the newcomers
+func (network *P2PNetwork) steadier(now time.Time) func(a *tree.Peer, b *tree.Peer) int {
+	return func(a *tree.Peer, b *tree.Peer) int {
+		stableA, stableB := network.stable(a.Id, now), network.stable(b.Id, now)
+
+		switch {
+		case stableA && !stableB:
+			return -1
+		case !stableA && stableB:
+			return 1
+		}
+
+		return 0
+	}
+}
+
+// replaces: reports whether the peer is stable and has free capacity for its siblings to join beneath it
+func (network *P2PNetwork) replaces(peer *tree.Peer, now time.Time) bool {
+	return peer.Capacity > 0 && network.stable(peer.Id, now)
+}
+
+// outranks: reports whether the peer should swap places with its parent. A stable peer with free capacity
+// moves above a newcomer, and a newcomer never moves above a stable peer. Otherwise, the peer needs
+// more free capacity than its parent (by more than one)
+func (network *P2PNetwork) outranks(peer *tree.Peer) bool {
+	if network.options.StableAfter > 0 {
+		now := time.Now()
+
+		stable, stableParent := network.stable(peer.Id, now), network.stable(peer.Parent.Id, now)
+
+		if stable != stableParent {
+			return stable && peer.Capacity > 0
+		}
+	}
+
+	return peer.Capacity > peer.Parent.Capacity+1
+}
+
+// Reputation: returns the session history of every peer id which joined the network, sorted by id
+func (network *P2PNetwork) Reputation() []entities.Reputation {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	now := time.Now()
+
+	reputation := make([]entities.Reputation, 0, len(network.sessions))
+
+	for id, s := range network.sessions {
+		reputation = append(reputation, entities.Reputation{
+			Id:             id,
+			Joins:          s.joins,
+			Failures:       s.failures,
+			AverageSession: s.average(now),
+			Online:         !s.since.IsZero(),
+			Stable:         network.stable(id, now),
+		})
+	}
+
+	sort.Slice(reputation, func(i, j int) bool {
+		return reputation[i].Id < reputation[j].Id
+	})
+
+	return reputation
+}
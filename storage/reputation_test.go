@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// age: moves the start of the current session of the peer for the given id back by the given time
+func age(network *P2PNetwork, id int, d time.Duration) {
+	s := network.sessions[id]
+	s.since = s.since.Add(-d)
+	network.sessions[id] = s
+}
+
+// joinAged: joins the given nodes, and ages the sessions of the given ids by an hour right after they join
+func joinAged(network *P2PNetwork, nodes []entities.Node, aged map[int]bool) {
+	for _, node := range nodes {
+		network.Join(node)
+
+		if aged[node.Id] {
+			age(network, node.Id, time.Hour)
+		}
+	}
+}
+
+func TestReputation(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{StableAfter: time.Hour}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 2})
+	network.Join(entities.Node{Id: 2, Capacity: 1})
+	network.Leave(2)
+	network.Join(entities.Node{Id: 2, Capacity: 1})
+	network.Fail(2)
+
+	age(network, 1, time.Hour)
+
+	expected := []entities.Reputation{
+		{Id: 1, Joins: 1, Failures: 0, AverageSession: time.Hour, Online: true, Stable: true},
+		{Id: 2, Joins: 2, Failures: 1, AverageSession: 0, Online: false, Stable: false},
+	}
+
+	result := network.Reputation()
+
+	if len(result) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, result)
+	}
+
+	for i := range expected {
+		// the sessions are measured by the clock, so the average is compared in seconds
+		result[i].AverageSession = result[i].AverageSession.Truncate(time.Second)
+
+		if result[i] != expected[i] {
+			t.Errorf("expected %+v, but got %+v", expected[i], result[i])
+		}
+	}
+}
+
+func TestReputationFailures(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{StableAfter: time.Hour}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 2})
+	age(network, 1, time.Hour)
+
+	network.Fail(1)
+	network.Join(entities.Node{Id: 1, Capacity: 2})
+	age(network, 1, time.Hour)
+
+	// the mean session is an hour, but it is halved by the failure
+	if network.stable(1, time.Now()) {
+		t.Errorf("expected %v, but got %v", false, true)
+	}
+
+	age(network, 1, time.Hour*2)
+
+	if !network.stable(1, time.Now()) {
+		t.Errorf("expected %v, but got %v", true, false)
+	}
+}
+
+func TestStablePlacement(t *testing.T) {
+	nodes := []entities.Node{
+		{Id: 1, Capacity: 3},
+		{Id: 2, Capacity: 5},
+		{Id: 3, Capacity: 0},
+		{Id: 4, Capacity: 0},
+		{Id: 5, Capacity: 0},
+	}
+
+	testTable := []struct {
+		name        string
+		stableAfter time.Duration
+		expected    []string
+	}{
+		{
+			name:        "most free capacity",
+			stableAfter: 0,
+			expected:    []string{"1(1/3)[ 2(3/5)[ 3(0/0) 4(0/0) 5(0/0) ] ]"},
+		},
+		{
+			/*
+				newcomers join the stable root until it is full
+
+					   1
+					 / | \
+					2  3  4
+					|
+					5
+			*/
+			name:        "stable parents first",
+			stableAfter: time.Hour,
+			expected:    []string{"1(3/3)[ 2(1/5)[ 5(0/0) ] 3(0/0) 4(0/0) ]"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{StableAfter: testCase.stableAfter}).(*P2PNetwork)
+
+			joinAged(network, nodes, map[int]bool{1: true})
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestStableReorder(t *testing.T) {
+	testTable := []struct {
+		name        string
+		stableAfter time.Duration
+		nodes       []entities.Node
+		aged        map[int]bool
+		leave       int
+		expected    []string
+	}{
+		{
+			name:        "most free capacity replaces the leaving peer",
+			stableAfter: 0,
+			nodes:       []entities.Node{{Id: 1, Capacity: 2}, {Id: 2, Capacity: 3}, {Id: 3, Capacity: 1}},
+			aged:        map[int]bool{1: true, 3: true},
+			leave:       1,
+			expected:    []string{"2(1/3)[ 3(0/1) ]"},
+		},
+		{
+			name:        "stable child replaces the leaving peer",
+			stableAfter: time.Hour,
+			nodes:       []entities.Node{{Id: 1, Capacity: 2}, {Id: 2, Capacity: 3}, {Id: 3, Capacity: 1}},
+			aged:        map[int]bool{1: true, 3: true},
+			leave:       1,
+			expected:    []string{"3(1/1)[ 2(0/3) ]"},
+		},
+		{
+			name:        "parent stays above without stability",
+			stableAfter: 0,
+			nodes:       []entities.Node{{Id: 1, Capacity: 1}, {Id: 2, Capacity: 1}, {Id: 3, Capacity: 1}, {Id: 4, Capacity: 0}},
+			aged:        map[int]bool{1: true, 3: true},
+			leave:       4,
+			expected:    []string{"1(1/1)[ 2(1/1)[ 3(0/1) ] ]"},
+		},
+		{
+			/*
+				3 is stable, so it swaps with the newcomer 2 once it has free capacity
+
+				  1           1
+				  |           |
+				  2    --->   3
+				  |           |
+				  3           2
+			*/
+			name:        "stable child moves above a newcomer",
+			stableAfter: time.Hour,
+			nodes:       []entities.Node{{Id: 1, Capacity: 1}, {Id: 2, Capacity: 1}, {Id: 3, Capacity: 1}, {Id: 4, Capacity: 0}},
+			aged:        map[int]bool{1: true, 3: true},
+			leave:       4,
+			expected:    []string{"1(1/1)[ 3(1/1)[ 2(0/1) ] ]"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{StableAfter: testCase.stableAfter}).(*P2PNetwork)
+
+			joinAged(network, testCase.nodes, testCase.aged)
+
+			_, err := network.Leave(testCase.leave)
+			if err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestReputationUndo(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 10}).(*P2PNetwork)
+
+	network.Join(entities.Node{Id: 1, Capacity: 2})
+	network.Join(entities.Node{Id: 2, Capacity: 1})
+	network.Leave(2)
+
+	// moving back and forth in the history is not a join or a leave of the peer
+	for i := 0; i < 2; i++ {
+		network.Undo()
+		network.Undo()
+		network.Redo()
+		network.Redo()
+	}
+
+	expected := []int{1, 1}
+
+	for i, r := range network.Reputation() {
+		if r.Joins != expected[i] {
+			t.Errorf("expected %v, but got %v", expected[i], r.Joins)
+		}
+	}
+
+	if network.sessions[2].since != (time.Time{}) {
+		t.Errorf("expected %v, but got %v", time.Time{}, network.sessions[2].since)
+	}
+}
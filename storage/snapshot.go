@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/treap"
+	"p2p-network-simulator/storage/tree"
+)
+
+// Snapshot: returns every peer of the network with its parent.
+// Trees are visited in order and peers in level order, so parents come before their children
+func (network *P2PNetwork) Snapshot() []entities.PeerRecord {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	records := make([]entities.PeerRecord, 0, len(network.ids))
+
+	for _, t := range network.topology {
+		for _, peer := range t.Peers() {
+			record := entities.PeerRecord{
+				Id:       peer.Id,
+				Capacity: peer.MaxCapacity,
+				Location: peer.Location,
+			}
+
+			if peer.Parent != nil {
+				record.Parent = peer.Parent.Id
+			}
+
+			_, record.Source = network.sources[peer.Id]
+
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// Restore: replaces the topology of the network with the given peers.
+// Parents must come before their children. If the records are not valid, then the network stays as it is
+func (network *P2PNetwork) Restore(records []entities.PeerRecord) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	topology := make([]*tree.Tree, 0)
+	peers := make(map[int]*tree.Peer)
+	sources := make(map[int]struct{})
+
+	for _, record := range records {
+		if record.Id < 1 {
+			return entities.ErrInvalidID
+		}
+
+		if record.Capacity < 0 {
+			return entities.ErrInvalidCapacity
+		}
+
+		if _, ok := peers[record.Id]; ok {
+			return entities.ErrDuplicateID.Errorf("id %d already reserved", record.Id)
+		}
+
+		peer := tree.NewPeer(entities.Node{Id: record.Id, Capacity: record.Capacity, Location: record.Location})
+		peers[record.Id] = peer
+
+		if record.Source && record.Parent != 0 {
+			return entities.ErrInvalidTopology.Errorf("source %d must be a root", record.Id)
+		}
+
+		if record.Source {
+			sources[record.Id] = struct{}{}
+		}
+
+		if record.Parent == 0 && !record.Source && network.options.SourcesOnly {
+			return entities.ErrInvalidTopology.Errorf("node %d must not be a root, only sources can be roots", record.Id)
+		}
+
+		if record.Parent == 0 {
+			topology = append(topology, tree.NewTree(peer))
+			continue
+		}
+
+		parent, ok := peers[record.Parent]
+		if !ok {
+			return entities.ErrInvalidTopology.Errorf("cannot locate parent id %d of node %d", record.Parent, record.Id)
+		}
+
+		if parent.Capacity == 0 {
+			return entities.ErrInvalidTopology.Errorf("node %d has no free capacity for node %d", record.Parent, record.Id)
+		}
+
+		parent.AddChild(peer)
+	}
+
+	// rebuild the treap and the ids from the restored peers
+	network.topology = topology
+	network.treap = treap.NewTreapWithTieBreak(network.options.TieBreak, network.options.Seed)
+	network.ids = make(map[int]struct{})
+	network.sources = sources
+	now := time.Now()
+
+	// the sessions of the peers in the network end, and the restored peers start new ones
+	for id := range network.heartbeats {
+		network.offline(id, now)
+	}
+
+	network.failed = make(map[int]time.Time)
+	network.heartbeats = make(map[int]time.Time)
+	network.queue = nil
+
+	// the restored topology is the start of a new history
+	network.history = history{}
+
+	for id, peer := range peers {
+		network.ids[id] = struct{}{}
+		network.heartbeats[id] = now
+		network.online(id, now)
+
+		if peer.Capacity > 0 {
+			network.treap.Insert(peer)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestSnapshot(t *testing.T) {
+	network := NewP2PNetwork().(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5, n7, n12} {
+		network.Join(node)
+	}
+
+	expected := []entities.PeerRecord{
+		{Id: 3, Capacity: 3, Parent: 0},
+		{Id: 4, Capacity: 0, Parent: 3},
+		{Id: 5, Capacity: 1, Parent: 3},
+		{Id: 7, Capacity: 5, Parent: 3},
+		{Id: 12, Capacity: 0, Parent: 7},
+	}
+
+	result := network.Snapshot()
+
+	if len(result) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, result)
+	}
+
+	for i := range result {
+		if result[i] != expected[i] {
+			t.Errorf("expected %v, but got %v", expected[i], result[i])
+		}
+	}
+
+	// the snapshot restores the same topology
+	restored := NewP2PNetwork().(*P2PNetwork)
+
+	err := restored.Restore(result)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	assertTrace(t, restored.Trace(), network.Trace())
+}
+
+func TestRestore(t *testing.T) {
+	testTable := []struct {
+		name          string
+		records       []entities.PeerRecord
+		expected      []string
+		expectedError error
+	}{
+		{
+			name: "happy case",
+			records: []entities.PeerRecord{
+				{Id: 1, Capacity: 1},
+				{Id: 2, Capacity: 0, Parent: 1},
+				{Id: 3, Capacity: 2},
+			},
+			expected:      []string{"1(1/1)[ 2(0/0) ]", "3(0/2)"},
+			expectedError: nil,
+		},
+		{
+			name: "duplicate id",
+			records: []entities.PeerRecord{
+				{Id: 1, Capacity: 1},
+				{Id: 1, Capacity: 1},
+			},
+			expected:      []string{"9(0/1)"},
+			expectedError: errors.New("id 1 already reserved"),
+		},
+		{
+			name: "child before parent",
+			records: []entities.PeerRecord{
+				{Id: 2, Capacity: 0, Parent: 1},
+				{Id: 1, Capacity: 1},
+			},
+			expected:      []string{"9(0/1)"},
+			expectedError: errors.New("cannot locate parent id 1 of node 2"),
+		},
+		{
+			name: "capacity exceeded",
+			records: []entities.PeerRecord{
+				{Id: 1, Capacity: 0},
+				{Id: 2, Capacity: 0, Parent: 1},
+			},
+			expected:      []string{"9(0/1)"},
+			expectedError: errors.New("node 1 has no free capacity for node 2"),
+		},
+		{
+			name:          "invalid id",
+			records:       []entities.PeerRecord{{Id: 0, Capacity: 1}},
+			expected:      []string{"9(0/1)"},
+			expectedError: errors.New("id must be a positive integer"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetwork().(*P2PNetwork)
+			network.Join(n9)
+
+			err := network.Restore(testCase.records)
+
+			if err == nil && testCase.expectedError != nil {
+				t.Errorf("expected %s, but got %v", testCase.expectedError.Error(), err)
+			}
+
+			if err != nil && testCase.expectedError == nil {
+				t.Errorf("expected %v, but got %s", testCase.expectedError, err.Error())
+			}
+
+			if err != nil && testCase.expectedError != nil && err.Error() != testCase.expectedError.Error() {
+				t.Errorf("expected %s, but got %s", testCase.expectedError.Error(), err.Error())
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
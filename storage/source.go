@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+// AddSource: a new source joining the network. A source is the root of a new tree,
+// and stays the root of it until it leaves
+func (network *P2PNetwork) AddSource(node entities.Node) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	// check whether the given node id is already reserved
+	_, ok := network.ids[node.Id]
+	if ok || network.queued(node.Id) {
+		return entities.ErrDuplicateID.Errorf("id %d already reserved", node.Id)
+	}
+
+	before := network.checkpoint()
+	defer network.commit(before)
+
+	peer := tree.NewPeer(node)
+
+	network.topology = append(network.topology, tree.NewTree(peer))
+
+	if peer.Capacity > 0 {
+		network.treap.Insert(peer)
+	}
+
+	network.ids[node.Id] = struct{}{}
+	network.sources[node.Id] = struct{}{}
+	now := time.Now()
+
+	network.heartbeats[node.Id] = now
+	network.online(node.Id, now)
+
+	// the capacity of the source can take waiting peers
+	network.drain()
+
+	return nil
+}
+
+// rehome: adds the given children of the leaving peer to the network, one subtree at a time
+func (network *P2PNetwork) rehome(peer *tree.Peer, children []*tree.Peer) {
+	for _, child := range children {
+		// delete child's tree peers from the treap
+		// to prevent from adding the child to its own tree
+		network.treap.DeepDelete(child)
+
+		// add to the network
+		network.reattach(child)
+
+		// the subtree is dropped or waits in the queue, if there is no place for it
+		if _, ok := network.ids[child.Id]; !ok {
+			continue
+		}
+
+		network.rehomed++
+
+		network.options.Logger.Debug("subtree rehomed", logging.F("node", child.Id), logging.F("left", peer.Id))
+
+		// re insert the deleted child's tree peers
+		network.deepInsert(child)
+	}
+}
+
+// reattach: adds the subtree of a peer which lost its parent to the network. With a queue policy,
+// the subtree waits for free capacity instead of starting a new tree, like a joining peer
+func (network *P2PNetwork) reattach(peer *tree.Peer) {
+	if network.options.Queue != NoQueue && network.parentFor(peer) == nil {
+		network.enqueue(peer)
+		return
+	}
+
+	network.add(peer)
+}
+
+// drop: removes the given peer and its subtree from the network. The peers are not in the treap
+func (network *P2PNetwork) drop(peer *tree.Peer) {
+	for _, p := range tree.NewTree(peer).Peers() {
+		delete(network.ids, p.Id)
+		delete(network.sources, p.Id)
+		delete(network.failed, p.Id)
+		delete(network.heartbeats, p.Id)
+		network.offline(p.Id, time.Now())
+	}
+
+	network.options.Logger.Debug("subtree dropped", logging.F("node", peer.Id))
+}
+
+// dropped: returns the ids of the given peers which are not in the network anymore, sorted.
+// The peer for the given id is not reported, since it left the network
+func (network *P2PNetwork) dropped(before map[int]int, left int) []int {
+	dropped := make([]int, 0)
+
+	for id := range before {
+		if _, ok := network.ids[id]; !ok && id != left && !network.queued(id) {
+			dropped = append(dropped, id)
+		}
+	}
+
+	sort.Ints(dropped)
+
+	return dropped
+}
+
+// encode: encodes the tree, labelled with its source
+func encode(t *tree.Tree, sources map[int]struct{}) string {
+	if _, ok := sources[t.GetRoot().Id]; ok {
+		return fmt.Sprintf("source %d: %s", t.GetRoot().Id, t.Encode())
+	}
+
+	return t.Encode()
+}
+
+// render: draws the tree, labelled with its source
+func render(t *tree.Tree, sources map[int]struct{}) string {
+	if _, ok := sources[t.GetRoot().Id]; ok {
+		return fmt.Sprintf("source %d\n%s", t.GetRoot().Id, t.Render())
+	}
+
+	return t.Render()
+}
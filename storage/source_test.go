@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestSources(t *testing.T) {
+	testTable := []struct {
+		name          string
+		sourcesOnly   bool
+		run           func(network *P2PNetwork) error
+		expected      []string
+		expectedError error
+	}{
+		{
+			name:        "no source",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				_, err := network.Join(n1)
+				return err
+			},
+			expected:      []string{},
+			expectedError: entities.ErrNoCapacity,
+		},
+		{
+			name:        "peers join beneath the source",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 1})
+				network.Join(entities.Node{Id: 2, Capacity: 1})
+				_, err := network.Join(entities.Node{Id: 3, Capacity: 0})
+				return err
+			},
+			expected: []string{"source 1: 1(1/1)[ 2(1/1)[ 3(0/0) ] ]"},
+		},
+		{
+			name:        "rejected without free capacity",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 1})
+				network.Join(entities.Node{Id: 2, Capacity: 0})
+				_, err := network.Join(entities.Node{Id: 3, Capacity: 0})
+				return err
+			},
+			expected:      []string{"source 1: 1(1/1)[ 2(0/0) ]"},
+			expectedError: entities.ErrNoCapacity,
+		},
+		{
+			// 3 would swap with 1 after 2 left, but the source stays the root
+			name:        "source is not reordered",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 1})
+				network.Join(entities.Node{Id: 2, Capacity: 1})
+				network.Join(entities.Node{Id: 3, Capacity: 5})
+				network.Join(entities.Node{Id: 4, Capacity: 0})
+				_, err := network.Leave(2)
+				return err
+			},
+			expected: []string{"source 1: 1(1/1)[ 3(1/5)[ 4(0/0) ] ]"},
+		},
+		{
+			name:        "source is not merged",
+			sourcesOnly: false,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 0})
+				network.Join(entities.Node{Id: 2, Capacity: 2})
+				network.Merge()
+				return nil
+			},
+			expected: []string{"source 1: 1(0/0)", "2(0/2)"},
+		},
+		{
+			name:        "duplicate id",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 1})
+				return network.AddSource(entities.Node{Id: 1, Capacity: 1})
+			},
+			expected:      []string{"source 1: 1(0/1)"},
+			expectedError: entities.ErrDuplicateID,
+		},
+		{
+			name:        "undo keeps the source",
+			sourcesOnly: true,
+			run: func(network *P2PNetwork) error {
+				network.AddSource(entities.Node{Id: 1, Capacity: 1})
+				network.Leave(1)
+				_, err := network.Undo()
+				return err
+			},
+			expected: []string{"source 1: 1(0/1)"},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{SourcesOnly: testCase.sourcesOnly, HistorySize: 10}).(*P2PNetwork)
+
+			err := testCase.run(network)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestSourceLeave(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{SourcesOnly: true}).(*P2PNetwork)
+
+	network.AddSource(entities.Node{Id: 1, Capacity: 2})
+	network.AddSource(entities.Node{Id: 10, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 0})
+	network.Join(entities.Node{Id: 3, Capacity: 0})
+
+	// 2 moves to the tree of 10, there is no place left for 3
+	report, err := network.Leave(1)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	expected := entities.LeaveReport{
+		Id:            1,
+		Reassignments: []entities.Reassignment{{Id: 2, OldParent: 1, NewParent: 10}},
+		Dropped:       []int{3},
+		Disruption:    entities.Disruption{Id: 1, ChangedParent: 1, Rehomed: 1, DepthChange: 0},
+	}
+
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %v, but got %v", expected, report)
+	}
+
+	assertTrace(t, network.Trace(), []string{"source 10: 10(1/1)[ 2(0/0) ]"})
+
+	// the id of the dropped peer is free again, but there is no place for it
+	if _, err := network.Join(entities.Node{Id: 3}); !errors.Is(err, entities.ErrNoCapacity) {
+		t.Errorf("expected %v, but got %v", entities.ErrNoCapacity, err)
+	}
+}
+
+func TestSourceSnapshot(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{SourcesOnly: true}).(*P2PNetwork)
+
+	network.AddSource(entities.Node{Id: 1, Capacity: 1})
+	network.Join(entities.Node{Id: 2, Capacity: 0})
+
+	records := network.Snapshot()
+
+	restored := NewP2PNetworkWithOptions(Options{SourcesOnly: true}).(*P2PNetwork)
+
+	if err := restored.Restore(records); err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	assertTrace(t, restored.Trace(), network.Trace())
+
+	// only sources can be roots
+	err := restored.Restore([]entities.PeerRecord{{Id: 1, Capacity: 1}})
+	if !errors.Is(err, entities.ErrInvalidTopology) {
+		t.Errorf("expected %v, but got %v", entities.ErrInvalidTopology, err)
+	}
+}
+
+func TestSourceResize(t *testing.T) {
+	testTable := []struct {
+		name     string
+		queue    QueuePolicy
+		expected []string
+		queued   []int // waiting after the capacity is lowered
+	}{
+		{
+			name:     "dropped without a queue",
+			queue:    NoQueue,
+			expected: []string{"source 1: 1(1/1)[ 3(0/0) ]"},
+			queued:   []int{},
+		},
+		{
+			// the capacity admits 2 again, and 3 joins beneath it
+			name:     "waits in the queue",
+			queue:    FIFO,
+			expected: []string{"source 1: 1(1/1)[ 2(1/1)[ 3(0/0) ] ]"},
+			queued:   []int{2},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{SourcesOnly: true, Queue: testCase.queue}).(*P2PNetwork)
+
+			network.AddSource(entities.Node{Id: 1, Capacity: 1})
+			network.Join(entities.Node{Id: 2, Capacity: 1})
+
+			// 2 has no other source to go beneath
+			if err := network.SetCapacity(1, 0); err != nil {
+				t.Fatalf("expected %v, but got %v", nil, err)
+			}
+
+			assertIds(t, queueIds(network), testCase.queued)
+
+			// 3 does not join beneath the subtree which is not in the network anymore
+			network.SetCapacity(1, 1)
+			network.Join(entities.Node{Id: 3, Capacity: 0})
+
+			assertTrace(t, network.Trace(), testCase.expected)
+
+			if stats := network.Stats(); stats.Rehomed != 0 {
+				t.Errorf("expected %v, but got %v", 0, stats.Rehomed)
+			}
+		})
+	}
+}
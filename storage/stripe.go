@@ -0,0 +1,460 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/domain/interfaces"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+/*
+StripedNetwork: the content is split into stripes, and each stripe has its own trees over the same peers, like SplitStream.
+Each peer is interior in one stripe only: it joins that stripe with its capacity, and the other stripes with zero capacity,
+so it is a leaf there. The forwarding load is spread, since every peer forwards a single stripe.
+*/
+type StripedNetwork struct {
+	// the trees of each stripe
+	stripes []*P2PNetwork
+
+	// the stripe each peer is interior in
+	interior map[int]int
+
+	// keeps track of the last heartbeat of each peer
+	heartbeats map[int]time.Time
+
+	// running totals of the disruptions of the leaves, summed over the stripes
+	disruptions disruptions
+
+	// options the network was created with
+	options Options
+
+	// using mutex to prevent from the concurrent accesses to the network
+	lock sync.Mutex
+}
+
+// NewStripedNetwork: creates new striped network with the given options. Zero stripes means two stripes.
+// The trees of the stripes are merged after each join and leave, since the leaves of a stripe cannot take children
+func NewStripedNetwork(options Options) interfaces.P2PNetwork {
+	if options.Logger == nil {
+		options.Logger = logging.Discard()
+	}
+
+	if options.Stripes <= 0 {
+		options.Stripes = 2
+	}
+
+	stripes := make([]*P2PNetwork, 0, options.Stripes)
+
+	for i := 0; i < options.Stripes; i++ {
+		stripes = append(stripes, NewP2PNetworkWithOptions(Options{
+			TieBreak:    options.TieBreak,
+			Seed:        options.Seed + int64(i),
+			AutoMerge:   true,
+			Promote:     options.Promote,
+			MaxDepth:    options.MaxDepth,
+			Locality:    options.Locality,
+			StableAfter: options.StableAfter,
+			Logger:      options.Logger.With(logging.F("stripe", i)),
+		}).(*P2PNetwork))
+	}
+
+	return &StripedNetwork{
+		stripes:    stripes,
+		interior:   make(map[int]int),
+		heartbeats: make(map[int]time.Time),
+		options:    options,
+	}
+}
+
+// Join: a new node joining every stripe. It is interior in the stripe with the least capacity
+func (network *StripedNetwork) Join(node entities.Node) (entities.Admission, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.interior[node.Id]; ok {
+		return entities.Admitted, entities.ErrDuplicateID.Errorf("id %d already reserved", node.Id)
+	}
+
+	interior := network.leastCapacity()
+
+	// the peer is in all of the stripes or none, so it joins only after every stripe has a place for it
+	for i, stripe := range network.stripes {
+		if err := stripe.placeable(tree.NewPeer(network.stripeNode(node, i, interior))); err != nil {
+			return entities.Admitted, err
+		}
+	}
+
+	for i, stripe := range network.stripes {
+		if _, err := stripe.Join(network.stripeNode(node, i, interior)); err != nil {
+			return entities.Admitted, err
+		}
+	}
+
+	network.interior[node.Id] = interior
+	network.heartbeats[node.Id] = time.Now()
+
+	return entities.Admitted, nil
+}
+
+// stripeNode: returns the node to join the given stripe with. The node has its capacity in its interior stripe only
+func (network *StripedNetwork) stripeNode(node entities.Node, stripe int, interior int) entities.Node {
+	capacity := 0
+	if stripe == interior {
+		capacity = node.Capacity
+	}
+
+	return entities.Node{Id: node.Id, Capacity: capacity, Location: node.Location}
+}
+
+// leastCapacity: returns the stripe with the least capacity of its interior peers, the lowest one on ties
+func (network *StripedNetwork) leastCapacity() int {
+	capacities := make([]int, len(network.stripes))
+
+	for i, stripe := range network.stripes {
+		for _, node := range stripe.Graph().Nodes {
+			capacities[i] += node.Capacity
+		}
+	}
+
+	least := 0
+	for i := range capacities {
+		if capacities[i] < capacities[least] {
+			least = i
+		}
+	}
+
+	return least
+}
+
+// Leave: a node leaving every stripe. The reassignments of the stripes are reported in the order of the stripes,
+// each with its stripe
+func (network *StripedNetwork) Leave(id int) (entities.LeaveReport, error) {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.interior[id]; !ok {
+		return entities.LeaveReport{}, entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	return network.leave(id), nil
+}
+
+// leave: removes the peer from every stripe. The disruption is summed over the stripes,
+// and the depth change is the one of the deepest stripe
+func (network *StripedNetwork) leave(id int) entities.LeaveReport {
+	report := entities.LeaveReport{Id: id, Reassignments: []entities.Reassignment{}, Dropped: []int{}}
+	report.Disruption.Id = id
+
+	depth := network.maxDepth()
+
+	for i, stripe := range network.stripes {
+		r, _ := stripe.Leave(id)
+
+		for _, reassignment := range r.Reassignments {
+			index := i
+			reassignment.Stripe = &index
+
+			report.Reassignments = append(report.Reassignments, reassignment)
+		}
+
+		report.Disruption.ChangedParent += r.Disruption.ChangedParent
+		report.Disruption.Rehomed += r.Disruption.Rehomed
+	}
+
+	report.Disruption.DepthChange = network.maxDepth() - depth
+	network.disruptions.record(report.Disruption)
+
+	delete(network.interior, id)
+	delete(network.heartbeats, id)
+
+	return report
+}
+
+// Trace: returns the trees of every stripe, labelled with their stripe: stripe 0: 1(1/1)[ 2(0/0) ]
+func (network *StripedNetwork) Trace() []string {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	trace := make([]string, 0, len(network.stripes))
+
+	for i, stripe := range network.stripes {
+		for _, t := range stripe.Trace() {
+			trace = append(trace, "stripe "+strconv.Itoa(i)+": "+t)
+		}
+	}
+
+	return trace
+}
+
+// Render: draws the trees of every stripe, labelled with their stripe
+func (network *StripedNetwork) Render() []string {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	drawings := make([]string, 0, len(network.stripes))
+
+	for i, stripe := range network.stripes {
+		for _, drawing := range stripe.Render() {
+			drawings = append(drawings, "stripe "+strconv.Itoa(i)+"\n"+drawing)
+		}
+	}
+
+	return drawings
+}
+
+// Graph: returns every peer once, with its children and capacity summed over the stripes and its depth in its interior stripe.
+// The edges are the links of every stripe
+func (network *StripedNetwork) Graph() entities.Graph {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	nodes := make(map[int]*entities.GraphNode)
+	edges := make(map[entities.GraphEdge]struct{})
+
+	for i, stripe := range network.stripes {
+		graph := stripe.Graph()
+
+		for _, n := range graph.Nodes {
+			node, ok := nodes[n.Id]
+			if !ok {
+				node = &entities.GraphNode{Id: n.Id}
+				nodes[n.Id] = node
+			}
+
+			node.Capacity += n.Capacity
+			node.Used += n.Used
+
+			if network.interior[n.Id] == i {
+				node.Depth = n.Depth
+			}
+		}
+
+		for _, e := range graph.Edges {
+			edges[e] = struct{}{}
+		}
+	}
+
+	graph := entities.Graph{
+		Nodes: make([]entities.GraphNode, 0, len(nodes)),
+		Edges: make([]entities.GraphEdge, 0, len(edges)),
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+
+	for edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	sortGraph(graph)
+
+	return graph
+}
+
+// Stats: returns the summary of every stripe together, with the forwarding load of the peers
+func (network *StripedNetwork) Stats() entities.Stats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	stats := entities.Stats{Peers: len(network.interior)}
+	forwarding := make(map[int]int)
+	children := 0
+
+	for _, stripe := range network.stripes {
+		s := stripe.Stats()
+
+		stats.Trees += s.Trees
+		stats.FreeCapacity += s.FreeCapacity
+		stats.Reorders += s.Reorders
+		stats.Rehomed += s.Rehomed
+
+		if s.MaxDepth > stats.MaxDepth {
+			stats.MaxDepth = s.MaxDepth
+		}
+
+		for _, node := range stripe.Graph().Nodes {
+			forwarding[node.Id] += node.Used
+			children += node.Used
+		}
+	}
+
+	for _, used := range forwarding {
+		if used > stats.MaxForwarding {
+			stats.MaxForwarding = used
+		}
+	}
+
+	if stats.Peers > 0 {
+		stats.MeanForwarding = float64(children) / float64(stats.Peers)
+	}
+
+	return stats
+}
+
+// Stripes: returns the summary of each stripe
+func (network *StripedNetwork) Stripes() []entities.StripeStats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	stripes := make([]entities.StripeStats, 0, len(network.stripes))
+
+	for i, stripe := range network.stripes {
+		s := stripe.Stats()
+
+		stats := entities.StripeStats{
+			Stripe:       i,
+			Peers:        s.Peers,
+			Trees:        s.Trees,
+			MaxDepth:     s.MaxDepth,
+			FreeCapacity: s.FreeCapacity,
+		}
+
+		for _, node := range stripe.Graph().Nodes {
+			if node.Used > 0 {
+				stats.Interior++
+			}
+		}
+
+		stripes = append(stripes, stats)
+	}
+
+	return stripes
+}
+
+// Locality: returns the edges between the regions for the trees of every stripe, in the order of the stripes
+func (network *StripedNetwork) Locality() []entities.TreeLocality {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	report := make([]entities.TreeLocality, 0)
+
+	for _, stripe := range network.stripes {
+		report = append(report, stripe.Locality()...)
+	}
+
+	return report
+}
+
+// Reputation: every peer joins every stripe, so the sessions of the first stripe are the sessions of the peers
+func (network *StripedNetwork) Reputation() []entities.Reputation {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	return network.stripes[0].Reputation()
+}
+
+// Disruption: returns the running totals of the disruptions of the leaves, with the records of the latest ones
+func (network *StripedNetwork) Disruption() entities.DisruptionStats {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	return network.disruptions.stats()
+}
+
+// maxDepth: depth of the deepest peer of every stripe
+func (network *StripedNetwork) maxDepth() int {
+	depth := 0
+
+	for _, stripe := range network.stripes {
+		if d := stripe.Stats().MaxDepth; d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
+
+// Merge: merges the trees of every stripe. Returns the number of trees merged into the others
+func (network *StripedNetwork) Merge() int {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	merged := 0
+
+	for _, stripe := range network.stripes {
+		merged += stripe.Merge()
+	}
+
+	return merged
+}
+
+// SetCapacity: changes the max capacity of a node in its interior stripe
+func (network *StripedNetwork) SetCapacity(id int, capacity int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	interior, ok := network.interior[id]
+	if !ok {
+		if capacity < 0 {
+			return entities.ErrInvalidCapacity
+		}
+
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	return network.stripes[interior].SetCapacity(id, capacity)
+}
+
+// Heartbeat: a node tells the network it is still alive
+func (network *StripedNetwork) Heartbeat(id int) error {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	if _, ok := network.interior[id]; !ok {
+		return entities.ErrNodeNotFound.Errorf("cannot locate id %d node", id)
+	}
+
+	network.heartbeats[id] = time.Now()
+
+	return nil
+}
+
+// Evict: removes the nodes without a heartbeat within the TTL of the network from every stripe, like they left
+func (network *StripedNetwork) Evict() []entities.LeaveReport {
+	// using locks to prevent from concurrent access
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	reports := make([]entities.LeaveReport, 0)
+
+	if network.options.TTL <= 0 {
+		return reports
+	}
+
+	now := time.Now()
+	expired := make([]int, 0)
+
+	for id, heartbeat := range network.heartbeats {
+		if now.Sub(heartbeat) > network.options.TTL {
+			expired = append(expired, id)
+		}
+	}
+
+	// evict in the order of ids, so the result does not depend on the map order
+	sort.Ints(expired)
+
+	for _, id := range expired {
+		reports = append(reports, network.leave(id))
+	}
+
+	return reports
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/treap"
+)
+
+func TestStripes(t *testing.T) {
+	testTable := []struct {
+		name       string
+		stripes    int
+		capacities []int
+		leave      int
+		expected   []string
+	}{
+		{
+			name:       "single stripe is a tree",
+			stripes:    1,
+			capacities: []int{2, 2, 1, 1},
+			expected:   []string{"stripe 0: 1(2/2)[ 2(1/2)[ 3(0/1) ] 4(0/1) ]"},
+		},
+		{
+			/*
+				peers are interior in the stripe with the least capacity: 1 and 3 in stripe 0, 2 and 4 in stripe 1
+
+					1          4
+				   / \         |
+				  2   3        2
+				      |       / \
+				      4      1   3
+			*/
+			name:       "each peer is interior in one stripe",
+			stripes:    2,
+			capacities: []int{2, 2, 1, 1},
+			expected: []string{
+				"stripe 0: 1(2/2)[ 2(0/0) 3(1/1)[ 4(0/0) ] ]",
+				"stripe 1: 4(1/1)[ 2(2/2)[ 1(0/0) 3(0/0) ] ]",
+			},
+		},
+		{
+			name:       "leave of an interior peer",
+			stripes:    2,
+			capacities: []int{2, 2, 1, 1},
+			leave:      2,
+			expected: []string{
+				"stripe 0: 1(1/2)[ 3(1/1)[ 4(0/0) ] ]",
+				"stripe 1: 4(1/1)[ 1(0/0) ]",
+				"stripe 1: 3(0/0)",
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewStripedNetwork(Options{Stripes: testCase.stripes}).(*StripedNetwork)
+
+			join(network, testCase.capacities...)
+
+			if testCase.leave > 0 {
+				_, err := network.Leave(testCase.leave)
+				if err != nil {
+					t.Fatalf("expected %v, but got %v", nil, err)
+				}
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestStripesInterior(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+
+	network := NewStripedNetwork(Options{Stripes: 4}).(*StripedNetwork)
+
+	for id := 1; id <= 200; id++ {
+		network.Join(entities.Node{Id: id, Capacity: random.Intn(5)})
+
+		if random.Intn(3) == 0 {
+			network.Leave(random.Intn(id) + 1)
+		}
+	}
+
+	// a peer forwards in its interior stripe only
+	for i, stripe := range network.stripes {
+		for _, node := range stripe.Graph().Nodes {
+			if node.Used > 0 && network.interior[node.Id] != i {
+				t.Errorf("expected %v, but got %v", network.interior[node.Id], i)
+			}
+		}
+	}
+}
+
+func TestStripesStats(t *testing.T) {
+	network := NewStripedNetwork(Options{Stripes: 2}).(*StripedNetwork)
+
+	join(network, 2, 2, 1, 1)
+
+	expected := entities.Stats{Peers: 4, Trees: 2, MaxDepth: 2, FreeCapacity: 0, MaxForwarding: 2, MeanForwarding: 1.5}
+
+	if stats := network.Stats(); stats != expected {
+		t.Errorf("expected %+v, but got %+v", expected, stats)
+	}
+
+	stripes := []entities.StripeStats{
+		{Stripe: 0, Peers: 4, Trees: 1, MaxDepth: 2, Interior: 2, FreeCapacity: 0},
+		{Stripe: 1, Peers: 4, Trees: 1, MaxDepth: 2, Interior: 2, FreeCapacity: 0},
+	}
+
+	result := network.Stripes()
+
+	if len(result) != len(stripes) {
+		t.Fatalf("expected %v, but got %v", stripes, result)
+	}
+
+	for i := range stripes {
+		if result[i] != stripes[i] {
+			t.Errorf("expected %+v, but got %+v", stripes[i], result[i])
+		}
+	}
+}
+
+func TestStripesJoinLeave(t *testing.T) {
+	network := NewStripedNetwork(Options{}).(*StripedNetwork)
+
+	join(network, 1)
+
+	_, err := network.Join(entities.Node{Id: 1, Capacity: 1})
+	if !errors.Is(err, entities.ErrDuplicateID) {
+		t.Errorf("expected %v, but got %v", entities.ErrDuplicateID, err)
+	}
+
+	_, err = network.Leave(2)
+	if !errors.Is(err, entities.ErrNodeNotFound) {
+		t.Errorf("expected %v, but got %v", entities.ErrNodeNotFound, err)
+	}
+}
+
+func TestStripesJoinRejected(t *testing.T) {
+	network := NewStripedNetwork(Options{MaxDepth: 2}).(*StripedNetwork)
+
+	join(network, 3, 2, 2, 3, 1)
+
+	trace := network.Trace()
+	reputation := network.Reputation()
+
+	// 6 fits in its interior stripe 0, but is too deep in stripe 1
+	_, err := network.Join(entities.Node{Id: 6, Capacity: 0})
+	if !errors.Is(err, entities.ErrDepthExceeded) {
+		t.Fatalf("expected %v, but got %v", entities.ErrDepthExceeded, err)
+	}
+
+	// none of the stripes changed
+	assertTrace(t, network.Trace(), trace)
+
+	if len(network.Reputation()) != len(reputation) {
+		t.Errorf("expected %v, but got %v", reputation, network.Reputation())
+	}
+}
+
+func TestStripesJoinRejectedRandom(t *testing.T) {
+	options := Options{MaxDepth: 2, TieBreak: treap.Random, Seed: 7}
+	first := NewStripedNetwork(options).(*StripedNetwork)
+	second := NewStripedNetwork(options).(*StripedNetwork)
+
+	join(first, 3, 3, 3, 1, 1)
+	join(second, 3, 3, 3, 1, 1)
+
+	// 6 has parents to pick from in stripe 0, but is too deep in stripe 1
+	_, err := first.Join(entities.Node{Id: 6, Capacity: 0})
+	if !errors.Is(err, entities.ErrDepthExceeded) {
+		t.Fatalf("expected %v, but got %v", entities.ErrDepthExceeded, err)
+	}
+
+	// the rejected join picks no parent, so both networks keep placing the peers the same way
+	for id := 7; id <= 10; id++ {
+		first.Join(entities.Node{Id: id, Capacity: 1})
+		second.Join(entities.Node{Id: id, Capacity: 1})
+	}
+
+	assertTrace(t, first.Trace(), second.Trace())
+}
+
+func TestStripesLeaveReport(t *testing.T) {
+	network := NewStripedNetwork(Options{}).(*StripedNetwork)
+
+	join(network, 2, 2, 1, 1)
+
+	// 2 is a leaf in stripe 0, so only its children in stripe 1 move
+	report, err := network.Leave(2)
+	if err != nil {
+		t.Fatalf("expected %v, but got %v", nil, err)
+	}
+
+	expected := []entities.Reassignment{
+		{Id: 1, OldParent: 2, NewParent: 4},
+		{Id: 3, OldParent: 2, NewParent: 0, Root: true},
+	}
+
+	if len(report.Reassignments) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, report.Reassignments)
+	}
+
+	for i, r := range report.Reassignments {
+		if r.Stripe == nil || *r.Stripe != 1 {
+			t.Errorf("expected stripe %v, but got %v", 1, r.Stripe)
+		}
+
+		r.Stripe = nil
+
+		if r != expected[i] {
+			t.Errorf("expected %+v, but got %+v", expected[i], r)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/logging"
+	"p2p-network-simulator/storage/tree"
+)
+
+/*
+Transaction: applies the operations in order to a clone of the network, and returns the resulting trace and stats.
+If any of the operations fails, then the network stays as it is. Otherwise, the clone replaces the topology of the
+network at once, unless it is a dry run. A committed transaction is undone as a single operation
+*/
+func (network *P2PNetwork) Transaction(operations []entities.Operation, dryRun bool) (entities.TransactionResult, error) {
+	// using locks to prevent from concurrent access, for the whole transaction
+	network.lock.Lock()
+	defer network.lock.Unlock()
+
+	clone := network.clone()
+
+	for index, operation := range operations {
+		err := clone.applyOperation(operation)
+		if err != nil {
+			return entities.TransactionResult{}, operationError(index, operation, err)
+		}
+	}
+
+	result := entities.TransactionResult{
+		Trace: clone.Trace(),
+		Stats: clone.Stats(),
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	before := network.checkpoint()
+
+	network.replace(clone)
+
+	network.commit(before)
+
+	result.Committed = true
+
+	return result, nil
+}
+
+// clone: returns a network with a copy of the topology, which can be changed without changing the network.
+// The clone keeps no history and logs nothing
+func (network *P2PNetwork) clone() *P2PNetwork {
+	options := network.options
+	options.HistorySize = 0
+	options.Logger = logging.Discard()
+	options.OnRepair = nil
+
+	clone := &P2PNetwork{
+		failed:     make(map[int]time.Time, len(network.failed)),
+		heartbeats: make(map[int]time.Time, len(network.heartbeats)),
+		sessions:   make(map[int]session, len(network.sessions)),
+		reorders:   network.reorders,
+		rehomed:    network.rehomed,
+		options:    options,
+		disruptions: disruptions{
+			totals:  network.disruptions.totals,
+			records: append([]entities.Disruption(nil), network.disruptions.records...),
+		},
+	}
+
+	for id, failedAt := range network.failed {
+		clone.failed[id] = failedAt
+	}
+
+	for id, heartbeat := range network.heartbeats {
+		clone.heartbeats[id] = heartbeat
+	}
+
+	for id, s := range network.sessions {
+		clone.sessions[id] = s
+	}
+
+	// rebuilds new peers at the same positions
+	clone.reset(network.positions())
+	// the seeded random tie break goes on where the network stopped, instead of starting over
+	clone.treap.ContinueRandom(network.treap)
+
+	clone.queue = copyQueue(network.queue)
+
+	return clone
+}
+
+// replace: takes the topology and the bookkeeping of the given clone. The options and the history stay as they are
+func (network *P2PNetwork) replace(clone *P2PNetwork) {
+	network.topology = clone.topology
+	network.treap = clone.treap
+	network.ids = clone.ids
+	network.sources = clone.sources
+	network.queue = clone.queue
+	network.failed = clone.failed
+	network.heartbeats = clone.heartbeats
+	network.sessions = clone.sessions
+	network.reorders = clone.reorders
+	network.rehomed = clone.rehomed
+	network.disruptions = clone.disruptions
+}
+
+// copySubtree: returns a copy of the peer and its subtree
+func copySubtree(peer *tree.Peer) *tree.Peer {
+	copied := tree.NewPeer(entities.Node{Id: peer.Id, Capacity: peer.MaxCapacity, Location: peer.Location})
+
+	for _, child := range peer.Children {
+		copied.AddChild(copySubtree(child))
+	}
+
+	return copied
+}
+
+// applyOperation: applies a single operation of a transaction
+func (network *P2PNetwork) applyOperation(operation entities.Operation) error {
+	if operation.Id < 1 {
+		return entities.ErrInvalidID
+	}
+
+	if operation.Capacity < 0 {
+		return entities.ErrInvalidCapacity
+	}
+
+	switch operation.Type {
+	case entities.OperationJoin:
+		_, err := network.Join(entities.Node{Id: operation.Id, Capacity: operation.Capacity, Location: operation.Location})
+		return err
+
+	case entities.OperationLeave:
+		_, err := network.Leave(operation.Id)
+		return err
+
+	case entities.OperationCapacity:
+		return network.SetCapacity(operation.Id, operation.Capacity)
+	}
+
+	return entities.ErrInvalidOperation.Errorf("unknown operation %q", operation.Type)
+}
+
+// operationError: prefixes the error with the failed operation, keeping the code of a domain error
+func operationError(index int, operation entities.Operation, err error) error {
+	message := fmt.Sprintf("operation %d (%s %d): %s", index, operation.Type, operation.Id, err.Error())
+
+	var domainError *entities.Error
+	if errors.As(err, &domainError) {
+		return domainError.Errorf("%s", message)
+	}
+
+	return errors.New(message)
+}
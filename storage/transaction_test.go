@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/treap"
+)
+
+func TestSetCapacity(t *testing.T) {
+	testTable := []struct {
+		name          string
+		id            int
+		capacity      int
+		expected      []string
+		expectedError error
+	}{
+		{
+			/*
+					3			3			5
+				  / | \\		|			|
+				 4  5  6  ->	4			7
+					|						|
+					7						6
+			*/
+			name:     "shrink re-homes the children which do not fit",
+			id:       3,
+			capacity: 1,
+			expected: []string{"3(1/1)[ 4(0/0) ]", "5(1/1)[ 7(1/5)[ 6(0/0) ] ]"},
+		},
+		{
+			name:     "grow moves the peer towards the root",
+			id:       5,
+			capacity: 5,
+			expected: []string{"5(2/5)[ 7(0/5) 3(2/3)[ 4(0/0) 6(0/0) ] ]"},
+		},
+		{
+			name:     "same capacity",
+			id:       7,
+			capacity: 5,
+			expected: []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+		},
+		{
+			name:          "not exists node",
+			id:            2,
+			capacity:      1,
+			expected:      []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			expectedError: entities.ErrNodeNotFound,
+		},
+		{
+			name:          "negative capacity",
+			id:            3,
+			capacity:      -1,
+			expected:      []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			expectedError: entities.ErrInvalidCapacity,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetwork().(*P2PNetwork)
+
+			for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+				network.Join(node)
+			}
+
+			err := network.SetCapacity(testCase.id, testCase.capacity)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			assertTrace(t, network.Trace(), testCase.expected)
+		})
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	initial := []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"}
+
+	testTable := []struct {
+		name          string
+		operations    []entities.Operation
+		dryRun        bool
+		expected      []string
+		expectedStats entities.Stats
+		expectedTrace []string // trace of the network after the transaction
+		expectedError error
+	}{
+		{
+			name: "dry run does not change the network",
+			operations: []entities.Operation{
+				{Type: entities.OperationLeave, Id: 4},
+				{Type: entities.OperationLeave, Id: 6},
+			},
+			dryRun:        true,
+			expected:      []string{"3(1/3)[ 5(1/1)[ 7(0/5) ] ]"},
+			expectedStats: entities.Stats{Peers: 3, Trees: 1, MaxDepth: 2, FreeCapacity: 7},
+			expectedTrace: initial,
+		},
+		{
+			name: "commit",
+			operations: []entities.Operation{
+				{Type: entities.OperationJoin, Id: 8, Capacity: 1},
+				{Type: entities.OperationCapacity, Id: 3, Capacity: 4},
+				{Type: entities.OperationLeave, Id: 4},
+			},
+			expected:      []string{"3(2/4)[ 5(1/1)[ 7(1/5)[ 8(0/1) ] ] 6(0/0) ]"},
+			expectedStats: entities.Stats{Peers: 5, Trees: 1, MaxDepth: 3, FreeCapacity: 7},
+			expectedTrace: []string{"3(2/4)[ 5(1/1)[ 7(1/5)[ 8(0/1) ] ] 6(0/0) ]"},
+		},
+		{
+			name: "a failed operation discards the transaction",
+			operations: []entities.Operation{
+				{Type: entities.OperationLeave, Id: 4},
+				{Type: entities.OperationLeave, Id: 9},
+			},
+			expectedTrace: initial,
+			expectedError: entities.ErrNodeNotFound,
+		},
+		{
+			name: "unknown operation",
+			operations: []entities.Operation{
+				{Type: "fail", Id: 4},
+			},
+			expectedTrace: initial,
+			expectedError: entities.ErrInvalidOperation,
+		},
+		{
+			name: "invalid id",
+			operations: []entities.Operation{
+				{Type: entities.OperationJoin, Id: 0},
+			},
+			expectedTrace: initial,
+			expectedError: entities.ErrInvalidID,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			network := NewP2PNetworkWithOptions(Options{HistorySize: 10}).(*P2PNetwork)
+
+			for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+				network.Join(node)
+			}
+
+			result, err := network.Transaction(testCase.operations, testCase.dryRun)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			if err == nil {
+				assertTrace(t, result.Trace, testCase.expected)
+
+				if result.Stats != testCase.expectedStats {
+					t.Errorf("expected %+v, but got %+v", testCase.expectedStats, result.Stats)
+				}
+
+				if result.Committed == testCase.dryRun {
+					t.Errorf("expected %v, but got %v", !testCase.dryRun, result.Committed)
+				}
+			}
+
+			assertTrace(t, network.Trace(), testCase.expectedTrace)
+		})
+	}
+}
+
+func TestUndoTransaction(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{HistorySize: 10}).(*P2PNetwork)
+
+	for _, node := range []entities.Node{n3, n4, n5, n6, n7} {
+		network.Join(node)
+	}
+
+	_, err := network.Transaction([]entities.Operation{
+		{Type: entities.OperationLeave, Id: 4},
+		{Type: entities.OperationLeave, Id: 6},
+		{Type: entities.OperationJoin, Id: 8, Capacity: 1},
+	}, false)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	// the transaction is a single version
+	if version := network.Version(); version != 6 {
+		t.Errorf("expected %d, but got %d", 6, version)
+	}
+
+	network.Undo()
+
+	assertTrace(t, network.Trace(), []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"})
+}
+
+func TestTransactionLocation(t *testing.T) {
+	network := NewP2PNetworkWithOptions(Options{Locality: true, HistorySize: 10}).(*P2PNetwork)
+
+	network.Join(l1)
+	network.Join(l2)
+
+	operations := make([]entities.Operation, 0, 3)
+	for _, node := range []entities.Node{l3, l4, l5} {
+		operations = append(operations, entities.Operation{Type: entities.OperationJoin, Id: node.Id, Capacity: node.Capacity, Location: node.Location})
+	}
+
+	if _, err := network.Transaction(operations, false); err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+
+	// the joining nodes are placed by their locations, like the joins in TestLocality
+	assertTrace(t, network.Trace(), []string{"1(2/4)[ 2(2/2)[ 3(0/0) 4(0/0) ] 5(0/0) ]"})
+}
+
+func TestTransactionRandom(t *testing.T) {
+	options := Options{TieBreak: treap.Random, Seed: 7, HistorySize: 10}
+	first := NewP2PNetworkWithOptions(options).(*P2PNetwork)
+	second := NewP2PNetworkWithOptions(options).(*P2PNetwork)
+
+	// the peers with the most free capacity tie, so the joining peers are placed randomly
+	for _, network := range []*P2PNetwork{first, second} {
+		network.Join(entities.Node{Id: 1, Capacity: 4})
+
+		for id := 2; id <= 9; id++ {
+			network.Join(entities.Node{Id: id, Capacity: 2})
+		}
+	}
+
+	_, err := first.Transaction([]entities.Operation{{Type: entities.OperationJoin, Id: 10, Capacity: 0}}, false)
+	if err != nil {
+		t.Fatalf("expected nil, but got %s", err.Error())
+	}
+	second.Join(entities.Node{Id: 10, Capacity: 0})
+
+	// the committed transaction continues the random source, so both networks keep picking the same parents
+	for id := 11; id <= 20; id++ {
+		first.Join(entities.Node{Id: id, Capacity: 0})
+		second.Join(entities.Node{Id: id, Capacity: 0})
+	}
+
+	assertTrace(t, first.Trace(), second.Trace())
+}
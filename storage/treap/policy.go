@@ -0,0 +1,83 @@
+package treap
+
+import (
+	"math/rand"
+	"sort"
+
+	"p2p-network-simulator/storage/tree"
+)
+
+// TieBreak: decides which peer is returned by Get when several peers have the same (most) free capacity.
+// Without a tie breaking policy, the chosen peer depends on the shape of the treap (insertion history)
+type TieBreak int
+
+const (
+	// ShallowestDepth: prefers the peer closest to the root of its tree, then the lowest id
+	ShallowestDepth TieBreak = iota
+
+	// LowestId: prefers the peer with the lowest id
+	LowestId
+
+	// Random: picks one of the candidates using the seeded random source of the treap.
+	// Identical sequences of operations on treaps with the same seed pick identical peers
+	Random
+)
+
+// String: returns the name of the tie breaking policy
+func (tb TieBreak) String() string {
+	switch tb {
+	case ShallowestDepth:
+		return "depth"
+	case LowestId:
+		return "id"
+	case Random:
+		return "random"
+	}
+
+	return "unknown"
+}
+
+// ParseTieBreak: returns the tie breaking policy for the given name
+func ParseTieBreak(name string) (TieBreak, bool) {
+	for _, tb := range []TieBreak{ShallowestDepth, LowestId, Random} {
+		if tb.String() == name {
+			return tb, true
+		}
+	}
+
+	return ShallowestDepth, false
+}
+
+// choose: returns the preferred peer among the given candidates (all have the same free capacity)
+func (tb TieBreak) choose(candidates []*tree.Peer, random *rand.Rand) *tree.Peer {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// sort by id first, so the result does not depend on the order the candidates were collected
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Id < candidates[j].Id
+	})
+
+	switch tb {
+	case LowestId:
+		return candidates[0]
+
+	case Random:
+		return candidates[random.Intn(len(candidates))]
+	}
+
+	best := candidates[0]
+	bestDepth := best.Depth()
+
+	for _, candidate := range candidates[1:] {
+		depth := candidate.Depth()
+
+		if depth < bestDepth {
+			best = candidate
+			bestDepth = depth
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,225 @@
+package treap
+
+import (
+	"math/rand"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+	"p2p-network-simulator/storage/tree"
+)
+
+/*
+tie breaking candidates, all of them have one free capacity
+
+		21
+	   /  \
+	  23   22
+	  |
+	  24
+	  |
+	  20
+*/
+var (
+	p21 = tree.NewPeer(entities.Node{Id: 21, Capacity: 3})
+	p22 = tree.NewPeer(entities.Node{Id: 22, Capacity: 1})
+	p23 = tree.NewPeer(entities.Node{Id: 23, Capacity: 2})
+	p24 = tree.NewPeer(entities.Node{Id: 24, Capacity: 2})
+	p20 = tree.NewPeer(entities.Node{Id: 20, Capacity: 1})
+)
+
+func init() {
+	p21.AddChild(p23)
+	p21.AddChild(p22)
+	p23.AddChild(p24)
+	p24.AddChild(p20)
+}
+
+func TestParseTieBreak(t *testing.T) {
+	testTable := []struct {
+		name     string
+		input    string
+		expected TieBreak
+		ok       bool
+	}{
+		{
+			name:     "depth",
+			input:    "depth",
+			expected: ShallowestDepth,
+			ok:       true,
+		},
+		{
+			name:     "id",
+			input:    "id",
+			expected: LowestId,
+			ok:       true,
+		},
+		{
+			name:     "random",
+			input:    "random",
+			expected: Random,
+			ok:       true,
+		},
+		{
+			name:     "unknown",
+			input:    "capacity",
+			expected: ShallowestDepth,
+			ok:       false,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, ok := ParseTieBreak(testCase.input)
+
+			if result != testCase.expected {
+				t.Errorf("expected %s, but got %s", testCase.expected, result)
+			}
+
+			if ok != testCase.ok {
+				t.Errorf("expected %v, but got %v", testCase.ok, ok)
+			}
+		})
+	}
+}
+
+func Test_choose(t *testing.T) {
+	testTable := []struct {
+		name       string
+		tieBreak   TieBreak
+		candidates []*tree.Peer
+		expected   *tree.Peer
+	}{
+		{
+			name:       "no candidates",
+			tieBreak:   ShallowestDepth,
+			candidates: nil,
+			expected:   nil,
+		},
+		{
+			name:       "shallowest depth",
+			tieBreak:   ShallowestDepth,
+			candidates: []*tree.Peer{p20, p24, p22},
+			expected:   p22,
+		},
+		{
+			name:       "shallowest depth, then lowest id",
+			tieBreak:   ShallowestDepth,
+			candidates: []*tree.Peer{p22, p24, p23},
+			expected:   p22,
+		},
+		{
+			name:       "lowest id",
+			tieBreak:   LowestId,
+			candidates: []*tree.Peer{p24, p22, p20},
+			expected:   p20,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.tieBreak.choose(testCase.candidates, rand.New(rand.NewSource(0)))
+
+			if result == nil && testCase.expected != nil {
+				t.Errorf("expected %d, but got %v", testCase.expected.Id, result)
+			}
+
+			if result != nil && testCase.expected == nil {
+				t.Errorf("expected %v, but got %d", testCase.expected, result.Id)
+			}
+
+			if result != nil && testCase.expected != nil && result.Id != testCase.expected.Id {
+				t.Errorf("expected %d, but got %d", testCase.expected.Id, result.Id)
+			}
+		})
+	}
+}
+
+func TestGetTieBreak(t *testing.T) {
+	// insertion order decides the shape of the treap, but not the result of Get
+	orders := [][]*tree.Peer{
+		{p22, p23, p24, p20},
+		{p20, p24, p23, p22},
+		{p23, p20, p24, p22},
+	}
+
+	testTable := []struct {
+		name     string
+		tieBreak TieBreak
+		expected *tree.Peer
+	}{
+		{
+			name:     "shallowest depth",
+			tieBreak: ShallowestDepth,
+			expected: p22,
+		},
+		{
+			name:     "lowest id",
+			tieBreak: LowestId,
+			expected: p20,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, order := range orders {
+				treap := NewTreapWithTieBreak(testCase.tieBreak, 0)
+
+				for _, peer := range order {
+					treap.Insert(peer)
+				}
+
+				result := treap.Get()
+
+				if result.Id != testCase.expected.Id {
+					t.Errorf("expected %d, but got %d", testCase.expected.Id, result.Id)
+				}
+			}
+		})
+	}
+
+	t.Run("seeded random", func(t *testing.T) {
+		for _, order := range orders {
+			first := NewTreapWithTieBreak(Random, 42)
+			second := NewTreapWithTieBreak(Random, 42)
+
+			for _, peer := range order {
+				first.Insert(peer)
+				second.Insert(peer)
+			}
+
+			for i := 0; i < 10; i++ {
+				a, b := first.Get(), second.Get()
+
+				if a.Id != b.Id {
+					t.Errorf("expected %d, but got %d", a.Id, b.Id)
+				}
+			}
+		}
+	})
+	t.Run("continued random", func(t *testing.T) {
+		for _, order := range orders {
+			first := NewTreapWithTieBreak(Random, 42)
+			second := NewTreapWithTieBreak(Random, 42)
+
+			for _, peer := range order {
+				first.Insert(peer)
+				second.Insert(peer)
+			}
+
+			for i := 0; i < 5; i++ {
+				first.Get()
+			}
+
+			// the second treap picks the same peers as the first one from now on, not from the seed
+			second.ContinueRandom(first)
+
+			for i := 0; i < 10; i++ {
+				a, b := first.Get(), second.Get()
+
+				if a.Id != b.Id {
+					t.Errorf("expected %d, but got %d", a.Id, b.Id)
+				}
+			}
+		}
+	})
+}
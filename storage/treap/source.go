@@ -0,0 +1,33 @@
+package treap
+
+import "math/rand"
+
+// source: splitmix64 random source. Unlike the sources of math/rand, its state can be copied,
+// so a copied treap keeps picking the same peers as the original one
+type source struct {
+	state uint64
+}
+
+func newSource(seed int64) *source {
+	return &source{state: uint64(seed)}
+}
+
+func (s *source) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+func (s *source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+var _ rand.Source64 = (*source)(nil)
@@ -1,6 +1,7 @@
 package treap
 
 import (
+	"math/rand"
 	"strconv"
 
 	"p2p-network-simulator/storage/tree"
@@ -12,22 +13,192 @@ import (
 // Use treap to keep track of peers which has the most free capacity
 type Treap struct {
 	root *node
+
+	// decides between peers which have the same free capacity
+	tieBreak TieBreak
+
+	// seeded random source, only used by the Random tie breaking policy
+	random *rand.Rand
+	source *source
 }
 
-// NewTreap: creates empty treap
+// NewTreap: creates empty treap which breaks ties by the shallowest depth, then the lowest id
 func NewTreap() *Treap {
+	return NewTreapWithTieBreak(ShallowestDepth, 0)
+}
+
+// NewTreapWithTieBreak: creates empty treap with the given tie breaking policy.
+// The seed is used by the Random policy, so the same seed always picks the same peers
+func NewTreapWithTieBreak(tieBreak TieBreak, seed int64) *Treap {
+	source := newSource(seed)
+
 	return &Treap{
-		root: nil,
+		root:     nil,
+		tieBreak: tieBreak,
+		random:   rand.New(source),
+		source:   source,
 	}
 }
 
-// Get: returns the peer which has the most free capacity (root node)
+// ContinueRandom: takes over the state of the random source of the given treap,
+// so this treap picks the same peers as the given one would pick from now on
+func (t *Treap) ContinueRandom(other *Treap) {
+	t.source.state = other.source.state
+}
+
+// Get: returns the peer which has the most free capacity.
+// If several peers have the most free capacity, then the tie breaking policy decides
 func (t *Treap) Get() *tree.Peer {
 	if t.root == nil {
 		return nil
 	}
 
-	return t.root.get()
+	// heap property: peers which have the same capacity as the root are connected to the root
+	capacity := t.root.peer.Capacity
+	candidates := make([]*tree.Peer, 0)
+
+	stack := []*node{t.root}
+
+	for len(stack) != 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.peer.Capacity != capacity {
+			continue
+		}
+
+		candidates = append(candidates, current.get())
+
+		if current.left != nil {
+			stack = append(stack, current.left)
+		}
+
+		if current.right != nil {
+			stack = append(stack, current.right)
+		}
+	}
+
+	return t.tieBreak.choose(candidates, t.random)
+}
+
+// GetWhere: returns the peer which has the most free capacity among the peers accepted by the given filter.
+// Visits every peer of the treap, so it is slower than Get. Returns nil, if no peer is accepted
+func (t *Treap) GetWhere(accept func(peer *tree.Peer) bool) *tree.Peer {
+	capacity := 0
+	candidates := make([]*tree.Peer, 0)
+
+	stack := make([]*node, 0)
+	if t.root != nil {
+		stack = append(stack, t.root)
+	}
+
+	for len(stack) != 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// heap property: the sub trees cannot have more free capacity than the best so far
+		if current.peer.Capacity < capacity {
+			continue
+		}
+
+		if accept(current.peer) {
+			if current.peer.Capacity > capacity {
+				capacity = current.peer.Capacity
+				candidates = candidates[:0]
+			}
+
+			candidates = append(candidates, current.get())
+		}
+
+		if current.left != nil {
+			stack = append(stack, current.left)
+		}
+
+		if current.right != nil {
+			stack = append(stack, current.right)
+		}
+	}
+
+	return t.tieBreak.choose(candidates, t.random)
+}
+
+// Any: reports whether the treap has a peer accepted by the given filter. Unlike GetWhere, the tie breaking
+// policy is not asked, so the random source stays as it is. Returns false for an empty treap
+func (t *Treap) Any(accept func(peer *tree.Peer) bool) bool {
+	stack := make([]*node, 0)
+	if t.root != nil {
+		stack = append(stack, t.root)
+	}
+
+	for len(stack) != 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if accept(current.peer) {
+			return true
+		}
+
+		if current.left != nil {
+			stack = append(stack, current.left)
+		}
+
+		if current.right != nil {
+			stack = append(stack, current.right)
+		}
+	}
+
+	return false
+}
+
+// GetClosest: returns the closest peer among the peers accepted by the given filter.
+// compare is negative if the first peer is closer than the second one, and zero if they are as close.
+// Among the closest peers, the one with the most free capacity is returned and the tie breaking policy decides on ties.
+// Visits every peer of the treap. Returns nil, if no peer is accepted
+func (t *Treap) GetClosest(accept func(peer *tree.Peer) bool, compare func(a *tree.Peer, b *tree.Peer) int) *tree.Peer {
+	candidates := make([]*tree.Peer, 0)
+
+	stack := make([]*node, 0)
+	if t.root != nil {
+		stack = append(stack, t.root)
+	}
+
+	for len(stack) != 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.left != nil {
+			stack = append(stack, current.left)
+		}
+
+		if current.right != nil {
+			stack = append(stack, current.right)
+		}
+
+		if !accept(current.peer) {
+			continue
+		}
+
+		if len(candidates) > 0 {
+			best := candidates[0]
+			c := compare(current.peer, best)
+
+			if c == 0 {
+				c = best.Capacity - current.peer.Capacity
+			}
+
+			if c > 0 {
+				continue
+			}
+
+			if c < 0 {
+				candidates = candidates[:0]
+			}
+		}
+
+		candidates = append(candidates, current.get())
+	}
+
+	return t.tieBreak.choose(candidates, t.random)
 }
 
 // Insert: inserts the given peer into the treap.
@@ -1,8 +1,10 @@
 package treap
 
 import (
+	"math"
 	"testing"
 
+	"p2p-network-simulator/domain/entities"
 	"p2p-network-simulator/storage/tree"
 )
 
@@ -214,6 +216,69 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetWhere(t *testing.T) {
+	/*
+			30 (free 2)
+		   /  \
+		  31   32 (free 3, 1)
+		  |
+		  33 (free 3)
+	*/
+	p30 := tree.NewPeer(entities.Node{Id: 30, Capacity: 4})
+	p31 := tree.NewPeer(entities.Node{Id: 31, Capacity: 4})
+	p32 := tree.NewPeer(entities.Node{Id: 32, Capacity: 1})
+	p33 := tree.NewPeer(entities.Node{Id: 33, Capacity: 3})
+
+	p30.AddChild(p31)
+	p30.AddChild(p32)
+	p31.AddChild(p33)
+
+	tr := NewTreap()
+	tr.DeepInsert(p30)
+
+	testTable := []struct {
+		name     string
+		accept   func(peer *tree.Peer) bool
+		expected *tree.Peer
+	}{
+		{
+			name:     "accept all",
+			accept:   func(peer *tree.Peer) bool { return true },
+			expected: p31,
+		},
+		{
+			name:     "depth less than two",
+			accept:   func(peer *tree.Peer) bool { return peer.Depth() < 2 },
+			expected: p31,
+		},
+		{
+			name:     "depth less than one",
+			accept:   func(peer *tree.Peer) bool { return peer.Depth() < 1 },
+			expected: p30,
+		},
+		{
+			name:     "leaves only",
+			accept:   func(peer *tree.Peer) bool { return len(peer.Children) == 0 },
+			expected: p33,
+		},
+		{
+			name:     "accept none",
+			accept:   func(peer *tree.Peer) bool { return false },
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := tr.GetWhere(testCase.accept)
+
+			if result != testCase.expected {
+				t.Errorf("expected %v, but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
 func TestDeepDelete(t *testing.T) {
 	testTable := []struct {
 		name     string
@@ -280,3 +345,64 @@ func TestDeepInsert(t *testing.T) {
 		})
 	}
 }
+
+func TestGetClosest(t *testing.T) {
+	// free capacities 3, 1, 1 at x 0, 10, 20
+	p40 := tree.NewPeer(entities.Node{Id: 40, Capacity: 3, Location: entities.Location{X: 0}})
+	p41 := tree.NewPeer(entities.Node{Id: 41, Capacity: 1, Location: entities.Location{X: 10}})
+	p42 := tree.NewPeer(entities.Node{Id: 42, Capacity: 1, Location: entities.Location{X: 20}})
+
+	tr := NewTreap()
+	tr.Insert(p40)
+	tr.Insert(p41)
+	tr.Insert(p42)
+
+	// closer to the given x
+	closerTo := func(x float64) func(a *tree.Peer, b *tree.Peer) int {
+		return func(a *tree.Peer, b *tree.Peer) int {
+			return int(math.Abs(a.Location.X-x) - math.Abs(b.Location.X-x))
+		}
+	}
+
+	testTable := []struct {
+		name     string
+		accept   func(peer *tree.Peer) bool
+		compare  func(a *tree.Peer, b *tree.Peer) int
+		expected *tree.Peer
+	}{
+		{
+			name:     "closest peer",
+			accept:   func(peer *tree.Peer) bool { return true },
+			compare:  closerTo(18),
+			expected: p42,
+		},
+		{
+			name:     "closest accepted peer",
+			accept:   func(peer *tree.Peer) bool { return peer.Id != 42 },
+			compare:  closerTo(18),
+			expected: p41,
+		},
+		{
+			name:     "most free capacity among the closest",
+			accept:   func(peer *tree.Peer) bool { return true },
+			compare:  func(a *tree.Peer, b *tree.Peer) int { return 0 },
+			expected: p40,
+		},
+		{
+			name:     "accept none",
+			accept:   func(peer *tree.Peer) bool { return false },
+			compare:  closerTo(0),
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := tr.GetClosest(testCase.accept, testCase.compare)
+
+			if result != testCase.expected {
+				t.Errorf("expected %v, but got %v", testCase.expected, result)
+			}
+		})
+	}
+}
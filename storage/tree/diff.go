@@ -0,0 +1,105 @@
+package tree
+
+import (
+	"sort"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+// placement: parent and depth of a peer in a forest
+type placement struct {
+	parent int // zero, if the peer is a root
+	depth  int
+}
+
+// placements: returns the parent and the depth of every peer in the forest
+func placements(forest []*Tree) map[int]placement {
+	places := make(map[int]placement)
+
+	for _, t := range forest {
+		for _, peer := range t.Peers() {
+			place := placement{depth: peer.Depth()}
+
+			if peer.Parent != nil {
+				place.parent = peer.Parent.Id
+			}
+
+			places[peer.Id] = place
+		}
+	}
+
+	return places
+}
+
+// Diff: lists the peers added and removed between the two forests,
+// and the parent, root and depth changes of the peers which are in both
+func Diff(from []*Tree, to []*Tree) entities.Diff {
+	before := placements(from)
+	after := placements(to)
+
+	diff := entities.Diff{
+		Added:   make([]int, 0),
+		Removed: make([]int, 0),
+		Parents: make([]entities.ParentChange, 0),
+		Roots:   make([]entities.RootChange, 0),
+		Depths:  make([]entities.DepthChange, 0),
+	}
+
+	ids := make([]int, 0, len(before)+len(after))
+
+	for id := range before {
+		ids = append(ids, id)
+	}
+
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	// list the changes in the order of ids, so the result does not depend on the map order
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		old, wasIn := before[id]
+		current, isIn := after[id]
+
+		switch {
+		case !wasIn:
+			diff.Added = append(diff.Added, id)
+			continue
+		case !isIn:
+			diff.Removed = append(diff.Removed, id)
+			continue
+		}
+
+		if old.parent != current.parent {
+			diff.Parents = append(diff.Parents, entities.ParentChange{Id: id, OldParent: old.parent, NewParent: current.parent})
+		}
+
+		if (old.parent == 0) != (current.parent == 0) {
+			diff.Roots = append(diff.Roots, entities.RootChange{Id: id, Root: current.parent == 0})
+		}
+
+		if old.depth != current.depth {
+			diff.Depths = append(diff.Depths, entities.DepthChange{Id: id, OldDepth: old.depth, NewDepth: current.depth})
+		}
+	}
+
+	return diff
+}
+
+// DiffTraces: diff of two outputs of the trace of a network
+func DiffTraces(from []string, to []string) (entities.Diff, error) {
+	before, err := DecodeAll(from)
+	if err != nil {
+		return entities.Diff{}, err
+	}
+
+	after, err := DecodeAll(to)
+	if err != nil {
+		return entities.Diff{}, err
+	}
+
+	return Diff(before, after), nil
+}
@@ -0,0 +1,88 @@
+package tree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"p2p-network-simulator/domain/entities"
+)
+
+func TestDiffTraces(t *testing.T) {
+	testTable := []struct {
+		name          string
+		from          []string
+		to            []string
+		expected      entities.Diff
+		expectedError error
+	}{
+		{
+			/*
+					3				7
+				  / | \\				|
+				 4  5  6   ---->	3
+					|			   / \\
+					7			  4   6
+			*/
+			name: "leave of a parent",
+			from: []string{"3(3/3)[ 4(0/0) 5(1/1)[ 7(0/5) ] 6(0/0) ]"},
+			to:   []string{"7(1/5)[ 3(2/3)[ 4(0/0) 6(0/0) ] ]"},
+			expected: entities.Diff{
+				Added:   []int{},
+				Removed: []int{5},
+				Parents: []entities.ParentChange{{Id: 3, OldParent: 0, NewParent: 7}, {Id: 7, OldParent: 5, NewParent: 0}},
+				Roots:   []entities.RootChange{{Id: 3, Root: false}, {Id: 7, Root: true}},
+				Depths: []entities.DepthChange{
+					{Id: 3, OldDepth: 0, NewDepth: 1},
+					{Id: 4, OldDepth: 1, NewDepth: 2},
+					{Id: 6, OldDepth: 1, NewDepth: 2},
+					{Id: 7, OldDepth: 2, NewDepth: 0},
+				},
+			},
+		},
+		{
+			name: "join of a new tree",
+			from: []string{"1(0/1)"},
+			to:   []string{"1(1/1)[ 2(0/0) ]", "3(0/0)"},
+			expected: entities.Diff{
+				Added:   []int{2, 3},
+				Removed: []int{},
+				Parents: []entities.ParentChange{},
+				Roots:   []entities.RootChange{},
+				Depths:  []entities.DepthChange{},
+			},
+		},
+		{
+			name: "same topology",
+			from: []string{"1(1/1)[ 2(0/0) ]"},
+			to:   []string{"1(1/1)[ 2(0/0) ]"},
+			expected: entities.Diff{
+				Added:   []int{},
+				Removed: []int{},
+				Parents: []entities.ParentChange{},
+				Roots:   []entities.RootChange{},
+				Depths:  []entities.DepthChange{},
+			},
+		},
+		{
+			name:          "invalid trace",
+			from:          []string{"1(1/1)["},
+			to:            []string{},
+			expectedError: entities.ErrInvalidTopology,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := DiffTraces(testCase.from, testCase.to)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			if err == nil && !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected %+v, but got %+v", testCase.expected, result)
+			}
+		})
+	}
+}
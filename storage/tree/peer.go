@@ -12,6 +12,7 @@ type Peer struct {
 	Capacity    int // free capacity
 	Parent      *Peer
 	Children    []*Peer
+	Location    entities.Location
 }
 
 // NewPeer: creates new peer. Initially, Capacity is equal to MaxCapacity
@@ -21,6 +22,7 @@ func NewPeer(node entities.Node) *Peer {
 		MaxCapacity: node.Capacity,
 		Capacity:    node.Capacity,
 		Children:    make([]*Peer, 0),
+		Location:    node.Location,
 	}
 }
 
@@ -65,3 +67,26 @@ func (p *Peer) RemoveChild(child *Peer) {
 	// child's parent removes from the parent and set it to nil
 	child.SetParent(nil)
 }
+
+// Depth: returns the number of edges between the peer and the root of its tree.
+// The root of a tree has depth zero
+func (p *Peer) Depth() int {
+	depth := 0
+
+	for current := p.Parent; current != nil; current = current.Parent {
+		depth++
+	}
+
+	return depth
+}
+
+// Root: returns the root of the tree which the peer belongs to
+func (p *Peer) Root() *Peer {
+	root := p
+
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return root
+}
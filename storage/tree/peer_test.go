@@ -159,3 +159,66 @@ func TestRemoveChild(t *testing.T) {
 		})
 	}
 }
+
+func TestDepth(t *testing.T) {
+	testTable := []struct {
+		name     string
+		peer     *Peer
+		expected int
+	}{
+		{
+			name:     "root",
+			peer:     p7,
+			expected: 0,
+		},
+		{
+			name:     "child of the root",
+			peer:     p8,
+			expected: 1,
+		},
+		{
+			name:     "grand child of the root",
+			peer:     p9,
+			expected: 2,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.peer.Depth()
+
+			if result != testCase.expected {
+				t.Errorf("expected %d, but got %d", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestRoot(t *testing.T) {
+	testTable := []struct {
+		name     string
+		peer     *Peer
+		expected *Peer
+	}{
+		{
+			name:     "root",
+			peer:     p7,
+			expected: p7,
+		},
+		{
+			name:     "grand child of the root",
+			peer:     p10,
+			expected: p7,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.peer.Root()
+
+			if result.Id != testCase.expected.Id {
+				t.Errorf("expected %d, but got %d", testCase.expected.Id, result.Id)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tree
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+Render: draws the tree like the tree command, one peer per line.
+
+	peer: id (#child / max capacity) depth
+
+	7 (2/2) depth 0
+	├── 6 (0/1) depth 1
+	└── 8 (2/3) depth 1
+	    ├── 9 (0/4) depth 2
+	    └── 10 (0/5) depth 2
+*/
+func (t *Tree) Render() string {
+	if t.root == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString(renderPeer(t.root, 0) + "\n")
+	recursiveRender(&builder, t.root, "", 1)
+
+	return builder.String()
+}
+
+// recursiveRender: recursively draws the children of the root, indented with the given prefix
+func recursiveRender(builder *strings.Builder, root *Peer, prefix string, depth int) {
+	for index, child := range root.Children {
+		branch, indent := "├── ", "│   "
+
+		// the last child closes the branch
+		if index == len(root.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		builder.WriteString(prefix + branch + renderPeer(child, depth) + "\n")
+		recursiveRender(builder, child, prefix+indent, depth+1)
+	}
+}
+
+// renderPeer: id (#child / max capacity) depth
+func renderPeer(peer *Peer, depth int) string {
+	return strconv.Itoa(peer.Id) + " (" + strconv.Itoa(len(peer.Children)) + "/" + strconv.Itoa(peer.MaxCapacity) + ") depth " + strconv.Itoa(depth)
+}
@@ -0,0 +1,60 @@
+package tree
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	// 1 ── 2 ── 4
+	//  \── 3
+	root := NewPeer(n1)
+	second := NewPeer(n2)
+	third := NewPeer(n3)
+	fourth := NewPeer(n4)
+
+	root.AddChild(second)
+	root.AddChild(third)
+	second.AddChild(fourth)
+
+	testTable := []struct {
+		name     string
+		tree     *Tree
+		expected string
+	}{
+		{
+			name: "happy case 1",
+			tree: t1,
+			expected: "7 (2/2) depth 0\n" +
+				"├── 6 (0/1) depth 1\n" +
+				"└── 8 (2/3) depth 1\n" +
+				"    ├── 9 (0/4) depth 2\n" +
+				"    └── 10 (0/5) depth 2\n",
+		},
+		{
+			name: "nested child before the last one",
+			tree: NewTree(root),
+			expected: "1 (2/1) depth 0\n" +
+				"├── 2 (1/2) depth 1\n" +
+				"│   └── 4 (0/4) depth 2\n" +
+				"└── 3 (0/3) depth 1\n",
+		},
+		{
+			name:     "single peer",
+			tree:     t3,
+			expected: "3 (0/3) depth 0\n",
+		},
+		{
+			name:     "empty tree",
+			tree:     t2,
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.tree.Render()
+
+			if result != testCase.expected {
+				t.Errorf("expected %q, but got %q", testCase.expected, result)
+			}
+		})
+	}
+}
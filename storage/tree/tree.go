@@ -2,6 +2,9 @@ package tree
 
 import (
 	"strconv"
+	"strings"
+
+	"p2p-network-simulator/domain/entities"
 )
 
 // Tree: it represents a sub network. It's connected peers and form a n-ary tree
@@ -55,6 +58,57 @@ func (t *Tree) Locate(id int) *Peer {
 	return nil
 }
 
+// Peers: returns every peer in the tree in level order
+func (t *Tree) Peers() []*Peer {
+	peers := make([]*Peer, 0)
+
+	if t.root == nil {
+		return peers
+	}
+
+	queue := make([]*Peer, 0)
+
+	queue = append(queue, t.root)
+
+	for len(queue) != 0 {
+
+		current := queue[0]
+		queue = queue[1:]
+
+		peers = append(peers, current)
+
+		if len(current.Children) > 0 {
+			queue = append(queue, current.Children...)
+		}
+	}
+
+	return peers
+}
+
+// Height: returns the number of levels in the tree. An empty tree has zero height
+func (t *Tree) Height() int {
+	return recursiveHeight(t.root)
+}
+
+// recursiveHeight: recursively finds the number of levels under the given peer
+func recursiveHeight(root *Peer) int {
+	if root == nil {
+		return 0
+	}
+
+	height := 0
+
+	for _, child := range root.Children {
+		h := recursiveHeight(child)
+
+		if h > height {
+			height = h
+		}
+	}
+
+	return height + 1
+}
+
 /*
 Encode: encodes the tree as a string.
 
@@ -100,3 +154,118 @@ func recursiveEncode(root *Peer) string {
 	temp += " ]"
 	return temp
 }
+
+/*
+Decode: decodes a tree encoded by Encode. The label of a tree of a source or of a stripe is skipped.
+
+	7(2/2)[ 6(0/1) 8(2/3)[ 9(0/4) 10(0/5) ] ]
+	source 7: 7(2/2)[ 6(0/1) 8(2/3)[ 9(0/4) 10(0/5) ] ]
+	stripe 0: 7(2/2)[ 6(0/0) 8(2/3)[ 9(0/0) 10(0/0) ] ]
+*/
+func Decode(encoded string) (*Tree, error) {
+	var root *Peer
+
+	if label, rest, ok := strings.Cut(encoded, ": "); ok && (strings.HasPrefix(label, "source ") || strings.HasPrefix(label, "stripe ")) {
+		encoded = rest
+	}
+
+	// parents of the current peer, the last one is the closest
+	stack := make([]*Peer, 0)
+
+	// number of children encoded for each peer, checked once every peer is decoded
+	encodedChildren := make(map[*Peer]int)
+
+	for _, token := range strings.Fields(encoded) {
+		if token == "]" {
+			if len(stack) == 0 {
+				return nil, entities.ErrInvalidTopology.Errorf("unbalanced brackets in %q", encoded)
+			}
+
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		peer, children, err := decodePeer(strings.TrimSuffix(token, "["))
+		if err != nil {
+			return nil, err
+		}
+
+		encodedChildren[peer] = children
+
+		switch {
+		case len(stack) > 0:
+			parent := stack[len(stack)-1]
+
+			if parent.Capacity == 0 {
+				return nil, entities.ErrInvalidTopology.Errorf("node %d has no free capacity for node %d", parent.Id, peer.Id)
+			}
+
+			parent.AddChild(peer)
+
+		case root == nil:
+			root = peer
+
+		default:
+			return nil, entities.ErrInvalidTopology.Errorf("more than one root in %q", encoded)
+		}
+
+		if strings.HasSuffix(token, "[") {
+			stack = append(stack, peer)
+		}
+	}
+
+	if root == nil || len(stack) != 0 {
+		return nil, entities.ErrInvalidTopology.Errorf("invalid encoded tree %q", encoded)
+	}
+
+	for peer, children := range encodedChildren {
+		if len(peer.Children) != children {
+			return nil, entities.ErrInvalidTopology.Errorf("node %d has %d children, but %d are encoded", peer.Id, children, len(peer.Children))
+		}
+	}
+
+	return NewTree(root), nil
+}
+
+// decodePeer: decodes id(#child/max capacity). Returns the peer and its number of children
+func decodePeer(token string) (*Peer, int, error) {
+	open := strings.Index(token, "(")
+	slash := strings.Index(token, "/")
+
+	if open < 1 || slash < open || !strings.HasSuffix(token, ")") {
+		return nil, 0, entities.ErrInvalidTopology.Errorf("invalid encoded peer %q", token)
+	}
+
+	id, err := strconv.Atoi(token[:open])
+	if err != nil || id < 1 {
+		return nil, 0, entities.ErrInvalidID.Errorf("invalid id in encoded peer %q", token)
+	}
+
+	children, err := strconv.Atoi(token[open+1 : slash])
+	if err != nil || children < 0 {
+		return nil, 0, entities.ErrInvalidTopology.Errorf("invalid number of children in encoded peer %q", token)
+	}
+
+	capacity, err := strconv.Atoi(token[slash+1 : len(token)-1])
+	if err != nil || capacity < children {
+		return nil, 0, entities.ErrInvalidCapacity.Errorf("invalid capacity in encoded peer %q", token)
+	}
+
+	return NewPeer(entities.Node{Id: id, Capacity: capacity}), children, nil
+}
+
+// DecodeAll: decodes every tree of a trace
+func DecodeAll(trace []string) ([]*Tree, error) {
+	forest := make([]*Tree, 0, len(trace))
+
+	for _, encoded := range trace {
+		t, err := Decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		forest = append(forest, t)
+	}
+
+	return forest, nil
+}
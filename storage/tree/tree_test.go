@@ -1,7 +1,10 @@
 package tree
 
 import (
+	"errors"
 	"testing"
+
+	"p2p-network-simulator/domain/entities"
 )
 
 /*
@@ -238,3 +241,150 @@ func TestEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestHeight(t *testing.T) {
+	testTable := []struct {
+		name     string
+		tree     *Tree
+		expected int
+	}{
+		{
+			name:     "happy case 1",
+			tree:     t1,
+			expected: 3,
+		},
+		{
+			name:     "single peer",
+			tree:     NewTree(NewPeer(n1)),
+			expected: 1,
+		},
+		{
+			name:     "empty tree",
+			tree:     NewTree(nil),
+			expected: 0,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.tree.Height()
+
+			if result != testCase.expected {
+				t.Errorf("expected %d, but got %d", testCase.expected, result)
+			}
+		})
+	}
+}
+
+func TestPeers(t *testing.T) {
+	testTable := []struct {
+		name     string
+		tree     *Tree
+		expected []int
+	}{
+		{
+			name:     "happy case 1",
+			tree:     t1,
+			expected: []int{7, 6, 8, 9, 10},
+		},
+		{
+			name:     "empty tree",
+			tree:     NewTree(nil),
+			expected: []int{},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := testCase.tree.Peers()
+
+			if len(result) != len(testCase.expected) {
+				t.Fatalf("expected %d peers, but got %d", len(testCase.expected), len(result))
+			}
+
+			for i, peer := range result {
+				if peer.Id != testCase.expected[i] {
+					t.Errorf("expected %d, but got %d", testCase.expected[i], peer.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	testTable := []struct {
+		name          string
+		encoded       string
+		expected      string // encoded again, if it is not the same as the given one
+		expectedError error
+	}{
+		{
+			name:    "happy case 1",
+			encoded: "7(2/2)[ 6(0/1) 8(2/3)[ 9(0/4) 10(0/5) ] ]",
+		},
+		{
+			name:     "tree of a source",
+			encoded:  "source 7: 7(1/2)[ 6(0/1) ]",
+			expected: "7(1/2)[ 6(0/1) ]",
+		},
+		{
+			name:     "tree of a stripe",
+			encoded:  "stripe 1: 7(1/2)[ 6(0/0) ]",
+			expected: "7(1/2)[ 6(0/0) ]",
+		},
+		{
+			name:    "single peer",
+			encoded: "3(0/3)",
+		},
+		{
+			name:          "unbalanced brackets",
+			encoded:       "7(1/2)[ 6(0/1)",
+			expectedError: entities.ErrInvalidTopology,
+		},
+		{
+			name:          "two roots",
+			encoded:       "7(0/2) 6(0/1)",
+			expectedError: entities.ErrInvalidTopology,
+		},
+		{
+			name:          "children do not match",
+			encoded:       "7(2/2)[ 6(0/1) ]",
+			expectedError: entities.ErrInvalidTopology,
+		},
+		{
+			name:          "more children than capacity",
+			encoded:       "7(2/1)[ 6(0/1) 8(0/1) ]",
+			expectedError: entities.ErrInvalidCapacity,
+		},
+		{
+			name:          "invalid id",
+			encoded:       "x(0/1)",
+			expectedError: entities.ErrInvalidID,
+		},
+		{
+			name:          "empty",
+			encoded:       "",
+			expectedError: entities.ErrInvalidTopology,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := Decode(testCase.encoded)
+
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("expected %v, but got %v", testCase.expectedError, err)
+			}
+
+			expected := testCase.encoded
+			if testCase.expected != "" {
+				expected = testCase.expected
+			}
+
+			// decoding and encoding again gives the same tree
+			if err == nil && result.Encode() != expected {
+				t.Errorf("expected %s, but got %s", expected, result.Encode())
+			}
+		})
+	}
+}